@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxBatchSize = 100
+
+var batchReferencePattern = regexp.MustCompile(`^\$(\d+)$`)
+
+// BatchOperation is one step of ExecuteBatch. Method names a transaction of BlockDriveInterface and Args holds its
+// arguments after the context, each encoded as JSON. An argument "$N" is replaced by the result of operation N.
+type BatchOperation struct {
+	Method string   `json:"method"`
+	Args   []string `json:"args"`
+}
+
+// BatchResult is the JSON encoded result of one operation of ExecuteBatch.
+type BatchResult struct {
+	Method string `json:"method"`
+	Result string `json:"result"`
+}
+
+// batchStub keeps the writes of a transaction, so that later reads see what earlier writes did, which Fabric itself
+// doesn't. Composite key queries, which read the indexes, see the writes as well. Range and rich queries aren't used by
+// the contract and only see the committed state.
+type batchStub struct {
+	shim.ChaincodeStubInterface
+	writes map[string][]byte
+}
+
+func (s *batchStub) GetState(key string) ([]byte, error) {
+	if value, ok := s.writes[key]; ok {
+		return value, nil
+	}
+	return s.ChaincodeStubInterface.GetState(key)
+}
+
+func (s *batchStub) PutState(key string, value []byte) error {
+	if err := s.ChaincodeStubInterface.PutState(key, value); err != nil {
+		return err
+	}
+	s.writes[key] = value
+	return nil
+}
+
+func (s *batchStub) DelState(key string) error {
+	if err := s.ChaincodeStubInterface.DelState(key); err != nil {
+		return err
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+func (s *batchStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	results := make([]*queryresult.KV, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := s.writes[kv.Key]; !ok {
+			results = append(results, kv)
+		}
+	}
+	for key, value := range s.writes {
+		if value != nil && strings.HasPrefix(key, prefix) {
+			results = append(results, &queryresult.KV{Key: key, Value: value})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Key < results[j].Key
+	})
+	return &batchIterator{results: results}, nil
+}
+
+// batchIterator iterates over the results of a composite key query of a batchStub.
+type batchIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *batchIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *batchIterator) Next() (*queryresult.KV, error) {
+	if len(i.results) == 0 {
+		return nil, fmt.Errorf("no more results")
+	}
+	kv := i.results[0]
+	i.results = i.results[1:]
+	return kv, nil
+}
+
+func (i *batchIterator) Close() error {
+	return nil
+}
+
+type batchContext struct {
+	contractapi.TransactionContextInterface
+	stub *batchStub
+}
+
+func newBatchContext(ctx contractapi.TransactionContextInterface) *batchContext {
+	return &batchContext{
+		TransactionContextInterface: ctx,
+		stub:                        &batchStub{ChaincodeStubInterface: ctx.GetStub(), writes: make(map[string][]byte)},
+	}
+}
+
+func (c *batchContext) GetStub() shim.ChaincodeStubInterface {
+	return c.stub
+}
+
+// readYourWrites returns a context whose reads see the writes made through it. Fabric only reads the committed state
+// until the transaction commits, so transactions which write the same key several times, like charging the usage of
+// every directory of a tree to one owner, must use it or later writes would overwrite earlier ones.
+func readYourWrites(ctx contractapi.TransactionContextInterface) contractapi.TransactionContextInterface {
+	if _, ok := ctx.(*batchContext); ok {
+		return ctx
+	}
+	return newBatchContext(ctx)
+}
+
+// resolveBatchArgs replaces the references to results of earlier operations.
+func resolveBatchArgs(args []string, results []*BatchResult) ([]string, error) {
+	resolved := make([]string, len(args))
+	for i, arg := range args {
+		match := batchReferencePattern.FindStringSubmatch(arg)
+		if match == nil {
+			resolved[i] = arg
+			continue
+		}
+		index, err := strconv.Atoi(match[1])
+		if err != nil || index >= len(results) {
+			return nil, fmt.Errorf("argument %d refers to unknown result %s", i, arg)
+		}
+		resolved[i] = results[index].Result
+	}
+	return resolved, nil
+}
+
+// executeBatchOperation calls a transaction of the contract with JSON encoded arguments and returns its JSON encoded
+// result.
+func executeBatchOperation(ctx contractapi.TransactionContextInterface, contract BlockDriveInterface, method string, args []string) (string, error) {
+	if _, ok := reflect.TypeOf((*BlockDriveInterface)(nil)).Elem().MethodByName(method); !ok || method == "ExecuteBatch" {
+		return "", fmt.Errorf("unknown method %s", method)
+	}
+	function := reflect.ValueOf(contract).MethodByName(method)
+	if function.Type().NumIn() != len(args)+1 {
+		return "", fmt.Errorf("%s expects %d arguments", method, function.Type().NumIn()-1)
+	}
+
+	in := []reflect.Value{reflect.ValueOf(&ctx).Elem()}
+	for i, arg := range args {
+		value := reflect.New(function.Type().In(i + 1))
+		if err := json.Unmarshal([]byte(arg), value.Interface()); err != nil {
+			return "", fmt.Errorf("invalid argument %d: %v", i, err)
+		}
+		in = append(in, value.Elem())
+	}
+
+	out := function.Call(in)
+	if err, _ := out[len(out)-1].Interface().(error); err != nil {
+		return "", err
+	}
+	if len(out) == 1 {
+		return "null", nil
+	}
+	bytes, err := json.Marshal(out[0].Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"testing"
+)
+
+type memoryStub struct {
+	shim.ChaincodeStubInterface
+	state map[string][]byte
+}
+
+func (s *memoryStub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *memoryStub) PutState(key string, value []byte) error {
+	return nil
+}
+
+func (s *memoryStub) DelState(key string) error {
+	return nil
+}
+
+func TestBatchStub(t *testing.T) {
+	stub := &batchStub{
+		ChaincodeStubInterface: &memoryStub{state: map[string][]byte{"a": []byte("1"), "b": []byte("2")}},
+		writes:                 make(map[string][]byte),
+	}
+	_ = stub.PutState("a", []byte("3"))
+	_ = stub.DelState("b")
+	if value, _ := stub.GetState("a"); string(value) != "3" {
+		t.Errorf("batch should read its own writes")
+	}
+	if value, _ := stub.GetState("b"); len(value) != 0 {
+		t.Errorf("batch should read its own deletes")
+	}
+}
+
+// pendingStub drops writes like Fabric does until the transaction commits.
+type pendingStub struct {
+	*shimtest.MockStub
+}
+
+func (s *pendingStub) PutState(key string, value []byte) error {
+	return nil
+}
+
+func (s *pendingStub) DelState(key string) error {
+	return nil
+}
+
+func TestBatchStub_GetStateByPartialCompositeKey(t *testing.T) {
+	committed := shimtest.NewMockStub("fabric-fs", nil)
+	committed.MockTransactionStart("tx0")
+	for _, parent := range []string{"a", "b"} {
+		key, _ := committed.CreateCompositeKey(parentIndex, []string{"child", parent})
+		_ = committed.PutState(key, []byte(`{"key":"`+parent+`"}`))
+	}
+	other, _ := committed.CreateCompositeKey(parentIndex, []string{"other", "d"})
+
+	stub := &batchStub{ChaincodeStubInterface: &pendingStub{committed}, writes: make(map[string][]byte)}
+	deleted, _ := stub.CreateCompositeKey(parentIndex, []string{"child", "a"})
+	added, _ := stub.CreateCompositeKey(parentIndex, []string{"child", "c"})
+	_ = stub.DelState(deleted)
+	_ = stub.PutState(added, []byte(`{"key":"c"}`))
+	_ = stub.PutState(other, []byte(`{"key":"d"}`))
+
+	iterator, err := stub.GetStateByPartialCompositeKey(parentIndex, []string{"child"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	parents := make([]string, 0)
+	for iterator.HasNext() {
+		kv, _ := iterator.Next()
+		item := new(SharedItem)
+		_ = json.Unmarshal(kv.GetValue(), item)
+		parents = append(parents, item.Key)
+	}
+	if len(parents) != 2 || parents[0] != "b" || parents[1] != "c" {
+		t.Errorf("composite key query should see the writes of the batch, got %v", parents)
+	}
+}
+
+func TestResolveBatchArgs(t *testing.T) {
+	results := []*BatchResult{{Method: "CreateDirectory", Result: `"key"`}}
+	args, err := resolveBatchArgs([]string{"$0", `["$0"]`, `"$1"`}, results)
+	if err != nil || args[0] != `"key"` || args[1] != `["$0"]` {
+		t.Errorf("fail to resolve references: %v %v", args, err)
+	}
+	if _, err = resolveBatchArgs([]string{"$1"}, results); err == nil {
+		t.Errorf("reference to a later result should fail")
+	}
+}
+
+func TestExecuteBatchOperation(t *testing.T) {
+	s := new(SmartContract)
+	if _, err := executeBatchOperation(nil, s, "GetName", nil); err == nil {
+		t.Errorf("methods outside of the interface should be rejected")
+	}
+	if _, err := executeBatchOperation(nil, s, "ExecuteBatch", []string{"[]"}); err == nil {
+		t.Errorf("nested batches should be rejected")
+	}
+	if _, err := executeBatchOperation(nil, s, "ReadDirectory", nil); err == nil {
+		t.Errorf("wrong number of arguments should be rejected")
+	}
+	if _, err := executeBatchOperation(nil, s, "ReadDirectory", []string{"1"}); err == nil {
+		t.Errorf("argument of wrong type should be rejected")
+	}
+}
+
+func TestExecuteBatch(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	ops := []*BatchOperation{
+		{Method: "CreateDirectory", Args: []string{`"reports"`, `"Private"`, `""`, "false"}},
+		{Method: "AddFile", Args: []string{"$0", `[{"name":"q1.pdf","cid":"cid1","size":10}]`}},
+		{Method: "AddCooperators", Args: []string{"$0", `["` + bob.ID() + `"]`, "false"}},
+		{Method: "ReadDirectory", Args: []string{"$0"}},
+	}
+
+	// Without committed writes the operations only see each other through the batch.
+	pending := l.as(alice).(*contractapi.TransactionContext)
+	pending.SetStub(&pendingStub{l.stub})
+	results, err := l.contract.ExecuteBatch(pending, ops)
+	l.must(err)
+	if len(results) != len(ops) {
+		t.Fatalf("expect a result per operation, got %d", len(results))
+	}
+	read := new(Directory)
+	l.must(json.Unmarshal([]byte(results[3].Result), read))
+	if read.Name != "reports" || len(read.Files) != 1 || !read.IsCooperator(bob.ID()) {
+		t.Errorf("later operations should see the writes of earlier ones, got %s", results[3].Result)
+	}
+
+	results, err = l.contract.ExecuteBatch(l.as(alice), ops)
+	l.must(err)
+	var key string
+	l.must(json.Unmarshal([]byte(results[0].Result), &key))
+	directory := l.read(key)
+	if directory.Name != "reports" || len(directory.Files) != 1 || !directory.IsCooperator(bob.ID()) {
+		t.Errorf("every operation should take effect, got %s", directory.ToString())
+	}
+
+	ops = []*BatchOperation{
+		{Method: "CreateDirectory", Args: []string{`"drafts"`, `"Private"`, `""`, "false"}},
+		{Method: "AddFile", Args: []string{`"missing"`, `[{"name":"q2.pdf","cid":"cid2"}]`}},
+	}
+	if results, err = l.contract.ExecuteBatch(l.as(alice), ops); err == nil || results != nil {
+		t.Errorf("a failing operation should fail the batch, got %v %v", results, err)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+// CooperatorTerm bounds the period in which a cooperator grant is effective. A zero date leaves that side open.
+type CooperatorTerm struct {
+	StartDate int64 `json:"startDate"`
+	DueDate   int64 `json:"dueDate"`
+}
+
+func (t *CooperatorTerm) IsActive(timestamp int64) bool {
+	if t.StartDate > 0 && timestamp < t.StartDate {
+		return false
+	}
+	return t.DueDate == 0 || timestamp < t.DueDate
+}
@@ -0,0 +1,123 @@
+package main
+
+import "testing"
+
+func TestAddCooperatorsWithTerm_RespectsRanks(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol := l.user("alice"), l.user("bob"), l.user("carol")
+	key := l.directory(alice, "project", Private)
+
+	l.must(l.contract.AddCooperators(l.as(alice), key, []string{bob.ID(), carol.ID()}, false))
+	l.must(l.contract.PromoteManagers(l.as(alice), key, []string{carol.ID()}, false))
+
+	if err := l.contract.AddCooperatorsWithTerm(l.as(bob), key, []string{carol.ID()}, 0, l.now+1, false); err != rankError {
+		t.Errorf("plain cooperator should not bound a manager, got %v", err)
+	}
+	if err := l.contract.AddCooperatorsWithTerm(l.as(bob), key, []string{alice.ID()}, l.now+1000, 0, false); err == nil {
+		t.Errorf("plain cooperator should not bound the creator")
+	}
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(carol), key, []string{bob.ID()}, 0, l.now+100, false))
+	if _, ok := l.read(key).CooperatorTerms[bob.ID()]; !ok {
+		t.Errorf("manager should bound a plain cooperator")
+	}
+}
+
+func TestTransferOwnership_DropsTermOfNewOwner(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	key := l.directory(alice, "project", Private)
+
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), key, []string{bob.ID()}, 0, l.now+100, false))
+	l.must(l.contract.TransferOwnership(l.as(alice), key, bob.ID(), false, false))
+
+	directory := l.read(key)
+	if _, ok := directory.CooperatorTerms[bob.ID()]; ok || !directory.IsCreator(bob.ID()) {
+		t.Errorf("new owner should not keep a bounded grant")
+	}
+}
+
+func TestAddCooperators_OwnTerm(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	key := l.directory(alice, "project", Private)
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), key, []string{bob.ID()}, 0, l.now+100, false))
+
+	if err := l.contract.AddCooperators(l.as(bob), key, []string{bob.ID()}, false); err != ownTermError {
+		t.Errorf("cooperator should not clear its own term, got %v", err)
+	}
+	if err := l.contract.AddCooperatorsWithTerm(l.as(bob), key, []string{bob.ID()}, 0, l.now+1000, false); err != ownTermError {
+		t.Errorf("cooperator should not extend its own term, got %v", err)
+	}
+	l.now += 200
+	if l.read(key).IsActiveCooperator(bob.ID(), l.now) {
+		t.Errorf("grant should end at its due date")
+	}
+}
+
+func TestAddCooperators_ClearTermRespectsRanks(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol := l.user("alice"), l.user("bob"), l.user("carol")
+	key := l.directory(alice, "project", Private)
+	l.must(l.contract.AddCooperators(l.as(alice), key, []string{bob.ID()}, false))
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), key, []string{carol.ID()}, 0, l.now+100, false))
+
+	if err := l.contract.AddCooperators(l.as(bob), key, []string{carol.ID()}, false); err != rankError {
+		t.Errorf("plain cooperator should not clear the term of another, got %v", err)
+	}
+	l.must(l.contract.AddCooperators(l.as(alice), key, []string{carol.ID()}, false))
+	if _, ok := l.read(key).CooperatorTerms[carol.ID()]; ok {
+		t.Errorf("creator should clear the term of a cooperator")
+	}
+}
+
+func TestAddCooperators_BoundedByGranter(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol, dave := l.user("alice"), l.user("bob"), l.user("carol"), l.user("dave")
+	key := l.directory(alice, "project", Private)
+	l.must(l.contract.AddCooperators(l.as(alice), key, []string{dave.ID()}, false))
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), key, []string{bob.ID()}, 0, l.now+100, false))
+
+	l.must(l.contract.AddCooperators(l.as(bob), key, []string{carol.ID(), dave.ID()}, false))
+	directory := l.read(key)
+	if term, ok := directory.CooperatorTerms[carol.ID()]; !ok || term.DueDate != l.now+100 {
+		t.Errorf("grant of a bounded cooperator should end with its own")
+	}
+	if _, ok := directory.CooperatorTerms[dave.ID()]; ok {
+		t.Errorf("re-adding a permanent cooperator should keep its grant")
+	}
+	eve := l.user("eve")
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(bob), key, []string{eve.ID()}, 0, l.now+500, false))
+	if term := l.read(key).CooperatorTerms[eve.ID()]; term.DueDate != l.now+100 {
+		t.Errorf("grant should be cut off at the term of its granter")
+	}
+}
+
+func TestAcceptInvitation_BoundedByInviter(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, dave := l.user("alice"), l.user("bob"), l.user("dave")
+	key := l.directory(alice, "project", Private)
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), key, []string{bob.ID()}, 0, l.now+100, false))
+
+	_, err := l.contract.InviteCooperator(l.as(bob), key, dave.ID(), 0, false)
+	l.must(err)
+	_, err = l.contract.AcceptInvitation(l.as(dave), key)
+	l.must(err)
+	if term, ok := l.read(key).CooperatorTerms[dave.ID()]; !ok || term.DueDate != l.now+100 {
+		t.Errorf("invitation of a bounded cooperator should end with its grant")
+	}
+}
+
+func TestRedeemShareLink_BoundedByCreator(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, dave := l.user("alice"), l.user("bob"), l.user("dave")
+	key := l.directory(alice, "project", Private)
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), key, []string{bob.ID()}, 0, l.now+100, false))
+
+	_, err := l.contract.CreateShareLink(l.withSecret(bob, testSecret), key, CooperatorRole, 0, 0)
+	l.must(err)
+	_, err = l.contract.RedeemShareLink(l.withSecret(dave, testSecret))
+	l.must(err)
+	if term, ok := l.read(key).CooperatorTerms[dave.ID()]; !ok || term.DueDate != l.now+100 {
+		t.Errorf("share link of a bounded cooperator should end with its grant")
+	}
+}
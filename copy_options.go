@@ -0,0 +1,13 @@
+package main
+
+// CopyOptions controls how CopyDirectory copies a tree.
+type CopyOptions struct {
+	// Shallow copies only the source directory. The copy refers to the original children instead of copies of them.
+	Shallow bool `json:"shallow"`
+	// MaxDepth limits the number of copied levels, the source directory being level one. Directories below the limit
+	// are left out of the copy. Zero copies the whole tree.
+	MaxDepth int `json:"maxDepth"`
+	// IncludeACL keeps the access lists of the copied directories. Otherwise copies inherit the access lists of the
+	// destination.
+	IncludeACL bool `json:"includeACL"`
+}
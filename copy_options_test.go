@@ -0,0 +1,127 @@
+package main
+
+import "testing"
+
+// tree creates a directory of identity under parent, holding one file named after the directory.
+func (l *testLedger) tree(identity *testIdentity, parent, name string) string {
+	key, err := l.contract.CreateDirectory(l.as(identity), name, Private, parent, false)
+	l.must(err)
+	_, err = l.contract.AddFile(l.as(identity), key, []*FileMeta{{Name: name + ".txt"}})
+	l.must(err)
+	return key
+}
+
+// checkCopies fails unless keys maps exactly sources to distinct copies which aren't sources themselves.
+func checkCopies(t *testing.T, keys map[string]string, sources ...string) {
+	t.Helper()
+	if len(keys) != len(sources) {
+		t.Errorf("expect copies of %d directories, got %v", len(sources), keys)
+	}
+	copies := make(map[string]bool)
+	for _, source := range sources {
+		key, ok := keys[source]
+		if !ok || copies[key] || key == source {
+			t.Errorf("expect a distinct copy of %s, got %v", source, keys)
+		}
+		copies[key] = true
+	}
+}
+
+func TestCopyDirectory_SameNamedFolders(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	destination := l.directory(alice, "destination", Private)
+	root := l.tree(alice, "", "project")
+	first := l.tree(alice, root, "docs")
+	archive := l.tree(alice, root, "archive")
+	second := l.tree(alice, archive, "docs")
+	_, err := l.contract.AddFile(l.as(alice), second, []*FileMeta{{Name: "old.txt"}})
+	l.must(err)
+
+	keys, err := l.contract.CopyDirectory(l.as(alice), root, destination)
+	l.must(err)
+	checkCopies(t, keys, root, first, archive, second)
+	if files := l.read(keys[first]).Files; len(files) != 1 {
+		t.Errorf("copy of the first folder should keep its own files, got %d", len(files))
+	}
+	if files := l.read(keys[second]).Files; len(files) != 2 {
+		t.Errorf("copy of the second folder should keep its own files, got %d", len(files))
+	}
+	if children := l.read(destination).Directories; len(children) != 1 || children[0] != keys[root] {
+		t.Errorf("copy should be attached to the destination, got %v", children)
+	}
+}
+
+func TestCopyDirectory_IntoItself(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	root := l.tree(alice, "", "project")
+	child := l.tree(alice, root, "docs")
+
+	keys, err := l.contract.CopyDirectory(l.as(alice), root, child)
+	l.must(err)
+	checkCopies(t, keys, root, child)
+	if children := l.read(keys[root]).Directories; len(children) != 1 || children[0] != keys[child] {
+		t.Errorf("copy should hold the copy of the child, got %v", children)
+	}
+	if children := l.read(keys[child]).Directories; len(children) != 0 {
+		t.Errorf("copy of the child should not hold the copy itself, got %v", children)
+	}
+	if children := l.read(child).Directories; len(children) != 1 || children[0] != keys[root] {
+		t.Errorf("copy should be attached to the child, got %v", children)
+	}
+
+	// The child now holds the first copy, which is copied along.
+	first := keys
+	keys, err = l.contract.CopyDirectory(l.as(alice), child, child)
+	l.must(err)
+	checkCopies(t, keys, child, first[root], first[child])
+	if children := l.read(child).Directories; len(children) != 2 || children[1] != keys[child] {
+		t.Errorf("copy should be attached to its source, got %v", children)
+	}
+}
+
+func TestCopyDirectoryWithOptions(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol := l.user("alice"), l.user("bob"), l.user("carol")
+	destination := l.directory(alice, "destination", Private)
+	root := l.tree(alice, "", "project")
+	middle := l.tree(alice, root, "docs")
+	leaf := l.tree(alice, middle, "drafts")
+	l.must(l.contract.AddCooperators(l.as(alice), root, []string{bob.ID()}, false))
+	l.must(l.contract.AddCooperators(l.as(alice), destination, []string{carol.ID()}, false))
+
+	keys, err := l.contract.CopyDirectoryWithOptions(l.as(alice), root, destination, CopyOptions{Shallow: true})
+	l.must(err)
+	checkCopies(t, keys, root)
+	if children := l.read(keys[root]).Directories; len(children) != 1 || children[0] != middle {
+		t.Errorf("shallow copy should refer to the original children, got %v", children)
+	}
+
+	keys, err = l.contract.CopyDirectoryWithOptions(l.as(alice), root, destination, CopyOptions{MaxDepth: 2})
+	l.must(err)
+	checkCopies(t, keys, root, middle)
+	if children := l.read(keys[middle]).Directories; len(children) != 0 {
+		t.Errorf("levels below the maximum depth should be left out, got %v", children)
+	}
+
+	keys, err = l.contract.CopyDirectory(l.as(alice), root, destination)
+	l.must(err)
+	checkCopies(t, keys, root, middle, leaf)
+	if copied := l.read(keys[root]); copied.IsCooperator(bob.ID()) || !copied.IsCooperator(carol.ID()) {
+		t.Errorf("copy should inherit the access list of the destination")
+	}
+
+	keys, err = l.contract.CopyDirectoryWithOptions(l.as(alice), root, destination, CopyOptions{IncludeACL: true})
+	l.must(err)
+	if copied := l.read(keys[root]); !copied.IsCooperator(bob.ID()) || copied.IsCooperator(carol.ID()) {
+		t.Errorf("copy should keep the access list of its source")
+	}
+	if !l.read(keys[root]).IsCreator(alice.ID()) {
+		t.Errorf("caller should own the copy")
+	}
+
+	if _, err = l.contract.CopyDirectoryWithOptions(l.as(alice), root, destination, CopyOptions{MaxDepth: -1}); err == nil {
+		t.Errorf("negative depth should be rejected")
+	}
+}
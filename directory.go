@@ -9,17 +9,31 @@ import (
 )
 
 type Directory struct {
-	Name        string            `json:"name"`
-	Directories []string          `json:"directories"`
-	Files       []*FileMeta       `json:"files"`
-	Creator     string            `json:"creator"`
-	Editor      string            `json:"editor"`
-	Date        int64             `json:"date"`
-	Cooperators []string          `json:"cooperators"`
-	Subscribers []*SubscriberMeta `json:"subscribers"`
-	Deleted     bool              `json:"deleted"`
-	IDNameMap   map[string]string `json:"idNameMap"`
-	Visibility  string            `json:"visibility"`
+	Name                    string                     `json:"name"`
+	Directories             []string                   `json:"directories"`
+	Files                   []*FileMeta                `json:"files"`
+	Creator                 string                     `json:"creator"`
+	Editor                  string                     `json:"editor"`
+	Date                    int64                      `json:"date"`
+	Cooperators             []string                   `json:"cooperators"`
+	Subscribers             []*SubscriberMeta          `json:"subscribers"`
+	Deleted                 bool                       `json:"deleted"`
+	IDNameMap               map[string]string          `json:"idNameMap"`
+	Visibility              string                     `json:"visibility"`
+	DefaultSubscriptionTerm int64                      `json:"defaultSubscriptionTerm"`
+	MaxSubscriptionTerm     int64                      `json:"maxSubscriptionTerm"`
+	SubscriptionPrice       int64                      `json:"subscriptionPrice"`
+	SubscriptionPeriod      int64                      `json:"subscriptionPeriod"`
+	Managers                []string                   `json:"managers"`
+	OrganizationGrants      []*OrganizationGrant       `json:"organizationGrants"`
+	Policies                []*AccessPolicy            `json:"policies"`
+	CooperatorTerms         map[string]*CooperatorTerm `json:"cooperatorTerms"`
+	FileShares              map[string][]string        `json:"fileShares"`
+	Target                  string                     `json:"target"`
+	MountMode               string                     `json:"mountMode"`
+	Usage                   int64                      `json:"usage"`
+	Quota                   int64                      `json:"quota"`
+	Tags                    []string                   `json:"tags"`
 }
 
 type Privilege int
@@ -33,9 +47,41 @@ const (
 const (
 	Public  = "Public"
 	Private = "Private"
+	// Unlisted directories can be read by anyone who knows their key, but are left out of queries and search.
+	Unlisted = "Unlisted"
 )
 
+//VisibilityError Reports a visibility which is none of Public, Private and Unlisted.
+type VisibilityError struct {
+	Visibility string
+}
+
+func (e *VisibilityError) Error() string {
+	return fmt.Sprintf("invalid visibility %q, expect %s, %s or %s", e.Visibility, Public, Private, Unlisted)
+}
+
+func ValidateVisibility(visibility string) error {
+	switch visibility {
+	case Public, Private, Unlisted:
+		return nil
+	}
+	return &VisibilityError{Visibility: visibility}
+}
+
 var privilegeError = fmt.Errorf("illegal access")
+var subscriptionTermError = fmt.Errorf("subscription term exceeds the maximum of directory")
+var creatorRemovalError = fmt.Errorf("can't remove the creator of directory")
+var rankError = fmt.Errorf("can't remove a cooperator of equal or higher rank")
+var ownTermError = fmt.Errorf("can't change the term of your own grant")
+var lastManagerError = fmt.Errorf("can't remove the last manager of directory")
+
+// Ranks of cooperators. A cooperator can only remove or demote cooperators of a lower rank.
+const (
+	NoRank = iota
+	CooperatorRank
+	ManagerRank
+	CreatorRank
+)
 
 func getDirectory(ctx contractapi.TransactionContextInterface, key string) (*Directory, error) {
 	directory := new(Directory)
@@ -75,23 +121,50 @@ func getDirectory(ctx contractapi.TransactionContextInterface, key string) (*Dir
 //	return directory, nil
 //}
 
-func CalculateDirectoryKey(timestamp int64, id, name string) string {
-	return SHA256(fmt.Sprintf("%s%d%s", id, timestamp, name))
+//CalculateDirectoryKey Derive the key of a new directory. The nonce tells apart directories of the same name created
+//within one transaction.
+func CalculateDirectoryKey(txID, id, name string, nonce int) string {
+	return SHA256(fmt.Sprintf("%s%s%s%d", txID, id, name, nonce))
+}
+
+const maxDirectoryKeyNonce = 64
+
+// newDirectoryKey returns a directory key which isn't in use yet.
+func newDirectoryKey(ctx contractapi.TransactionContextInterface, id, name string) (string, error) {
+	for nonce := 0; nonce < maxDirectoryKeyNonce; nonce++ {
+		key := CalculateDirectoryKey(ctx.GetStub().GetTxID(), id, name, nonce)
+		bytes, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return "", err
+		}
+		if len(bytes) == 0 {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("directory key conflict")
+}
+
+//CalculateCopyKey Derive the key of a copy. The index tells apart copies of the same source within one transaction.
+func CalculateCopyKey(txID, destination, source string, index int) string {
+	return SHA256(fmt.Sprintf("%s%s%s%d", txID, destination, source, index))
 }
 
 func NewDirectory(name, creatorID, creatorName string, visibility string, date int64) *Directory {
 	return &Directory{
-		Name:        name,
-		Directories: make([]string, 0),
-		Files:       make([]*FileMeta, 0),
-		Creator:     creatorID,
-		Date:        date,
-		Editor:      creatorID,
-		Cooperators: []string{creatorID},
-		Subscribers: make([]*SubscriberMeta, 0),
-		Deleted:     false,
-		IDNameMap:   map[string]string{creatorID: creatorName},
-		Visibility:  visibility,
+		Name:            name,
+		Directories:     make([]string, 0),
+		Files:           make([]*FileMeta, 0),
+		Creator:         creatorID,
+		Date:            date,
+		Editor:          creatorID,
+		Cooperators:     []string{creatorID},
+		Subscribers:     make([]*SubscriberMeta, 0),
+		Deleted:         false,
+		IDNameMap:       map[string]string{creatorID: creatorName},
+		Visibility:      visibility,
+		Managers:        make([]string, 0),
+		CooperatorTerms: make(map[string]*CooperatorTerm),
+		FileShares:      make(map[string][]string),
 	}
 }
 
@@ -105,17 +178,184 @@ func (d *Directory) CheckPrivilege(ctx contractapi.TransactionContextInterface,
 		return false, err
 	}
 
+	ok, err := d.PrincipalHasPrivilege(ctx, id, privilege, timestamp.Seconds)
+	if err != nil || ok {
+		return ok, err
+	}
+	ok, err = d.MatchOrganizationGrants(ctx, privilege)
+	if err != nil || ok {
+		return ok, err
+	}
+	return d.MatchPolicies(ctx, privilege)
+}
+
+//PrincipalHasPrivilege Check the privilege of a user given by the access lists, directly or through a group. Unlike
+//CheckPrivilege it works for users other than the caller, because organization grants and policies, which depend on
+//the certificate of the caller, are left out.
+func (d *Directory) PrincipalHasPrivilege(ctx contractapi.TransactionContextInterface, id string, privilege Privilege, timestamp int64) (bool, error) {
+	if d.HasPrivilege(id, privilege, timestamp) {
+		return true, nil
+	}
+	groups, err := d.MemberGroups(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	for _, group := range groups {
+		if d.HasPrivilege(group, privilege, timestamp) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//MatchPolicies Evaluate the access policies of the organization of the caller giving the privilege against the
+//certificate attributes of the caller.
+func (d *Directory) MatchPolicies(ctx contractapi.TransactionContextInterface, privilege Privilege) (bool, error) {
+	if len(d.Policies) == 0 {
+		return false, nil
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, err
+	}
+	for _, accessPolicy := range d.Policies {
+		if accessPolicy.MSPID != mspID || !RoleGrants(accessPolicy.Role, privilege) {
+			continue
+		}
+		policy, err := ParsePolicy(accessPolicy.Expression)
+		if err != nil {
+			return false, err
+		}
+		ok, err := policy.Evaluate(ctx.GetClientIdentity().GetAttributeValue)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//SetPolicy Set the access policy of a role for the identities of an organization. An empty expression removes the
+//policy.
+func (d *Directory) SetPolicy(role string, mspID string, expression string) {
+	remains := make([]*AccessPolicy, 0)
+	for _, policy := range d.Policies {
+		if policy.Role == role && policy.MSPID == mspID {
+			continue
+		}
+		remains = append(remains, policy)
+	}
+	if expression != "" {
+		remains = append(remains, &AccessPolicy{Role: role, MSPID: mspID, Expression: expression})
+	}
+	d.Policies = remains
+}
+
+//MatchOrganizationGrants Check whether a grant to the organization of the caller gives the privilege.
+func (d *Directory) MatchOrganizationGrants(ctx contractapi.TransactionContextInterface, privilege Privilege) (bool, error) {
+	if len(d.OrganizationGrants) == 0 {
+		return false, nil
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, err
+	}
+	for _, grant := range d.OrganizationGrants {
+		if !grant.Grants(privilege) {
+			continue
+		}
+		ok, err := grant.Matches(mspID, ctx.GetClientIdentity().GetAttributeValue)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//SetOrganizationGrant Add a grant, replacing the grant of the same organization if there is one.
+func (d *Directory) SetOrganizationGrant(grant *OrganizationGrant) {
+	d.RemoveOrganizationGrants(grant.MSPID)
+	d.OrganizationGrants = append(d.OrganizationGrants, grant)
+}
+
+func (d *Directory) RemoveOrganizationGrants(mspID string) {
+	remains := make([]*OrganizationGrant, 0)
+	for _, grant := range d.OrganizationGrants {
+		if grant.MSPID == mspID {
+			continue
+		}
+		remains = append(remains, grant)
+	}
+	d.OrganizationGrants = remains
+}
+
+//HasPrivilege Check the privilege of a user or group listed directly in the access lists.
+func (d *Directory) HasPrivilege(id string, privilege Privilege, timestamp int64) bool {
 	switch privilege {
 	case Subscriber:
-		if !d.IsCooperator(id) && !d.IsSubscribers(id, timestamp.Seconds) {
-			return false, nil
-		}
+		return d.IsActiveCooperator(id, timestamp) || d.IsSubscribers(id, timestamp)
 	case Cooperator:
-		if !d.IsCooperator(id) {
-			return false, nil
+		return d.IsActiveCooperator(id, timestamp)
+	}
+	return true
+}
+
+//MemberGroups Return the references of the groups in the access lists which have id as a member. Groups which no
+//longer exist are ignored.
+func (d *Directory) MemberGroups(ctx contractapi.TransactionContextInterface, id string) ([]string, error) {
+	references := make([]string, 0)
+	for _, cooperator := range d.Cooperators {
+		references = append(references, cooperator)
+	}
+	for _, subscriber := range d.Subscribers {
+		references = append(references, subscriber.Id)
+	}
+
+	record := make(map[string]bool)
+	groups := make([]string, 0)
+	for _, reference := range references {
+		if !IsGroupReference(reference) || record[reference] {
+			continue
+		}
+		record[reference] = true
+
+		group, err := getGroup(ctx, reference)
+		if err != nil {
+			continue
+		}
+		if group.IsMember(id) {
+			groups = append(groups, reference)
 		}
 	}
-	return true, nil
+	return groups, nil
+}
+
+//CopyAccess Replace the access lists of the directory with copies of the access lists of source. Ownership and
+//pricing are not copied.
+func (d *Directory) CopyAccess(source *Directory) {
+	d.Cooperators = append([]string{}, source.Cooperators...)
+	d.Managers = append([]string{}, source.Managers...)
+	d.Subscribers = make([]*SubscriberMeta, 0)
+	for _, subscriber := range source.Subscribers {
+		meta := *subscriber
+		d.Subscribers = append(d.Subscribers, &meta)
+	}
+	d.IDNameMap = make(map[string]string)
+	for id, name := range source.IDNameMap {
+		d.IDNameMap[id] = name
+	}
+	d.CooperatorTerms = make(map[string]*CooperatorTerm)
+	for id, term := range source.CooperatorTerms {
+		copied := *term
+		d.CooperatorTerms[id] = &copied
+	}
+	d.OrganizationGrants = append([]*OrganizationGrant{}, source.OrganizationGrants...)
+	d.Policies = append([]*AccessPolicy{}, source.Policies...)
 }
 
 func (d *Directory) ToString() string {
@@ -123,6 +363,16 @@ func (d *Directory) ToString() string {
 	return string(bytes)
 }
 
+//IsReadableByKey Check whether anyone knowing the key may read the directory.
+func (d *Directory) IsReadableByKey() bool {
+	return d.Visibility == Public || d.Visibility == Unlisted
+}
+
+//IsListed Check whether the directory may show up in queries and search results of users without access.
+func (d *Directory) IsListed() bool {
+	return d.Visibility == Public
+}
+
 func (d *Directory) IsCreator(id string) bool {
 	return id == d.Creator
 }
@@ -136,6 +386,114 @@ func (d *Directory) IsCooperator(id string) bool {
 	return false
 }
 
+//IsActiveCooperator Check whether id is a cooperator and its grant is effective at timestamp.
+func (d *Directory) IsActiveCooperator(id string, timestamp int64) bool {
+	if !d.IsCooperator(id) {
+		return false
+	}
+	term, ok := d.CooperatorTerms[id]
+	return !ok || term.IsActive(timestamp)
+}
+
+//GrantDueDate Return the latest date until which id is a cooperator at timestamp, directly or through a group. Zero
+//means the grant doesn't end. Grants made by a cooperator are cut off at this date, so they don't outlast its own.
+func (d *Directory) GrantDueDate(ctx contractapi.TransactionContextInterface, id string, timestamp int64) (int64, error) {
+	groups, err := d.MemberGroups(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	var dueDate int64 = -1
+	for _, principal := range append([]string{id}, groups...) {
+		if !d.HasPrivilege(principal, Cooperator, timestamp) {
+			continue
+		}
+		term, ok := d.CooperatorTerms[principal]
+		if !ok || term.DueDate == 0 {
+			return 0, nil
+		}
+		if term.DueDate > dueDate {
+			dueDate = term.DueDate
+		}
+	}
+	if dueDate < 0 {
+		return 0, privilegeError
+	}
+	return dueDate, nil
+}
+
+//AddCooperatorsUntil Add new cooperators whose grant ends at dueDate, or permanent ones if dueDate is zero. Existing
+//cooperators keep their terms.
+func (d *Directory) AddCooperatorsUntil(ids []string, names []string, dueDate int64) error {
+	added := make([]string, 0)
+	for _, id := range ids {
+		if !d.IsCooperator(id) {
+			added = append(added, id)
+		}
+	}
+	d.AddCooperators(ids, names)
+	if dueDate == 0 {
+		return nil
+	}
+	return d.SetCooperatorTerm(added, 0, dueDate)
+}
+
+//GrantCooperators Add cooperators on behalf of granter with the term from startDate until dueDate, cut off at limit
+//unless limit is zero. Setting, changing or clearing the term of an existing cooperator is subject to the rank rules of
+//CheckRemoval, and nobody can change their own term. Existing cooperators without a term are left as they are if no
+//term is given.
+func (d *Directory) GrantCooperators(granter string, ids []string, names []string, startDate, dueDate, limit int64) error {
+	requested := startDate != 0 || dueDate != 0
+	for _, id := range ids {
+		current, ok := d.CooperatorTerms[id]
+		if id == granter && ok && (current.StartDate != startDate || current.DueDate != dueDate) {
+			return ownTermError
+		}
+	}
+	if limit > 0 && (dueDate == 0 || dueDate > limit) {
+		dueDate = limit
+	}
+	changed := make([]string, 0)
+	for _, id := range ids {
+		if d.IsCooperator(id) {
+			current, ok := d.CooperatorTerms[id]
+			if !ok && !requested {
+				continue
+			}
+			if ok && current.StartDate == startDate && current.DueDate == dueDate {
+				continue
+			}
+			if id == granter {
+				return ownTermError
+			}
+			if err := d.CheckRemoval(granter, []string{id}); err != nil {
+				return err
+			}
+		}
+		changed = append(changed, id)
+	}
+	d.AddCooperators(ids, names)
+	return d.SetCooperatorTerm(changed, startDate, dueDate)
+}
+
+//SetCooperatorTerm Bound the grants of cooperators to the given period. Zero start and due date make the grants
+//permanent again.
+func (d *Directory) SetCooperatorTerm(ids []string, startDate, dueDate int64) error {
+	if d.CooperatorTerms == nil {
+		d.CooperatorTerms = make(map[string]*CooperatorTerm)
+	}
+	for _, id := range ids {
+		if startDate == 0 && dueDate == 0 {
+			delete(d.CooperatorTerms, id)
+			continue
+		}
+		if d.IsCreator(id) {
+			return fmt.Errorf("can't limit the access of the creator")
+		}
+		d.CooperatorTerms[id] = &CooperatorTerm{StartDate: startDate, DueDate: dueDate}
+	}
+	return nil
+}
+
 func (d *Directory) IsSubscribers(id string, timestamp int64) bool {
 	for _, subscriber := range d.Subscribers {
 		if subscriber.Id == id && subscriber.DueDate > timestamp {
@@ -145,6 +503,93 @@ func (d *Directory) IsSubscribers(id string, timestamp int64) bool {
 	return false
 }
 
+//GetSubscriber Return the subscription of id with the latest due date, or nil if id never subscribed.
+func (d *Directory) GetSubscriber(id string) *SubscriberMeta {
+	var result *SubscriberMeta
+	for _, subscriber := range d.Subscribers {
+		if subscriber.Id == id && (result == nil || subscriber.DueDate > result.DueDate) {
+			result = subscriber
+		}
+	}
+	return result
+}
+
+func (d *Directory) IsManager(id string) bool {
+	for _, manager := range d.Managers {
+		if manager == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Directory) Rank(id string) int {
+	switch {
+	case !d.IsCooperator(id):
+		return NoRank
+	case d.IsCreator(id):
+		return CreatorRank
+	case d.IsManager(id):
+		return ManagerRank
+	default:
+		return CooperatorRank
+	}
+}
+
+//CheckRemoval Check whether operator may strip the given cooperators of their rank. The creator can't be removed,
+//cooperators can only remove themselves or cooperators of a lower rank, and at least one manager has to remain.
+func (d *Directory) CheckRemoval(operator string, ids []string) error {
+	removed := make(map[string]bool)
+	for _, id := range ids {
+		rank := d.Rank(id)
+		if rank == NoRank {
+			continue
+		}
+		if d.IsCreator(id) {
+			return creatorRemovalError
+		}
+		if id != operator && d.Rank(operator) <= rank {
+			return rankError
+		}
+		removed[id] = true
+	}
+
+	managers := 0
+	for _, id := range append([]string{d.Creator}, d.Managers...) {
+		if d.Rank(id) >= ManagerRank && !removed[id] {
+			managers++
+		}
+	}
+	if managers == 0 {
+		return lastManagerError
+	}
+	return nil
+}
+
+//PromoteManagers Raise cooperators to the manager rank. Ids which are not cooperators are ignored.
+func (d *Directory) PromoteManagers(ids []string) {
+	for _, id := range ids {
+		if d.IsCooperator(id) && !d.IsCreator(id) && !d.IsManager(id) {
+			d.Managers = append(d.Managers, id)
+		}
+	}
+}
+
+func (d *Directory) DemoteManagers(ids []string) {
+	record := make(map[string]bool)
+	remains := make([]string, 0)
+	for _, i := range ids {
+		record[i] = true
+	}
+	for _, i := range d.Managers {
+		if record[i] {
+			continue
+		}
+		remains = append(remains, i)
+	}
+	d.Managers = remains
+}
+
 func (d *Directory) AddIDNameMap(id []string, names []string) {
 	for index, item := range id {
 		d.IDNameMap[item] = names[index]
@@ -182,20 +627,20 @@ func (d *Directory) RemoveCooperators(id []string) {
 		remains = append(remains, i)
 	}
 	d.Cooperators = remains
+	d.DemoteManagers(id)
+	for _, i := range id {
+		delete(d.CooperatorTerms, i)
+	}
 }
 
 func (d *Directory) AddSubscribers(ids []string, names []string, date int64) {
 	newSubscriberArray := make([]*SubscriberMeta, 0)
-	existedSubscriberMap := make(map[string]bool)
-
-	for _, subscriber := range d.Subscribers {
-		if subscriber.DueDate > date {
-			existedSubscriberMap[subscriber.Id] = true
-		}
-	}
 
 	for _, id := range ids {
-		if existedSubscriberMap[id] {
+		if subscriber := d.GetSubscriber(id); subscriber != nil {
+			if subscriber.DueDate < date {
+				subscriber.DueDate = date
+			}
 			continue
 		}
 
@@ -211,6 +656,89 @@ func (d *Directory) AddSubscribers(ids []string, names []string, date int64) {
 	}
 }
 
+//SubscriptionDueDate Resolve the due date of a subscription starting at timestamp. An explicit due date wins over a
+//duration, and the default term of the directory is used when neither is given. Only the default term is cut down to
+//the maximum term, explicit terms exceeding it are rejected.
+func (d *Directory) SubscriptionDueDate(timestamp, duration, dueDate int64) (int64, error) {
+	if duration < 0 || dueDate < 0 {
+		return 0, fmt.Errorf("subscription term can't be negative")
+	}
+
+	explicit := duration > 0 || dueDate > 0
+	if dueDate == 0 {
+		if duration == 0 {
+			duration = d.DefaultSubscriptionTerm
+		}
+		if duration == 0 {
+			duration = validity
+		}
+		dueDate = timestamp + duration
+	}
+	if dueDate <= timestamp {
+		return 0, fmt.Errorf("subscription due date must be in the future")
+	}
+
+	if d.MaxSubscriptionTerm > 0 && dueDate-timestamp > d.MaxSubscriptionTerm {
+		if explicit {
+			return 0, subscriptionTermError
+		}
+		dueDate = timestamp + d.MaxSubscriptionTerm
+	}
+	return dueDate, nil
+}
+
+//RenewSubscribers Extend the subscriptions of existing subscribers by duration, counted from their current due date or
+//from timestamp if the subscription already expired. Ids which are not subscribers are ignored.
+func (d *Directory) RenewSubscribers(ids []string, duration int64, timestamp int64) error {
+	for _, id := range ids {
+		subscriber := d.GetSubscriber(id)
+		if subscriber == nil {
+			continue
+		}
+
+		start := subscriber.DueDate
+		if start < timestamp {
+			start = timestamp
+		}
+		dueDate, err := d.SubscriptionDueDate(start, duration, 0)
+		if err != nil {
+			return err
+		}
+		if d.MaxSubscriptionTerm > 0 && dueDate-timestamp > d.MaxSubscriptionTerm {
+			if duration > 0 {
+				return subscriptionTermError
+			}
+			dueDate = timestamp + d.MaxSubscriptionTerm
+		}
+
+		d.RemoveSubscribers([]string{id})
+		d.Subscribers = append(d.Subscribers, &SubscriberMeta{Id: id, DueDate: dueDate})
+	}
+	return nil
+}
+
+//PruneExpiredSubscribers Remove the subscriptions which expired before timestamp and return the ids of the removed
+//subscribers. Names of removed subscribers are dropped unless they are still cooperators.
+func (d *Directory) PruneExpiredSubscribers(timestamp int64) []string {
+	record := make(map[string]bool)
+	expired := make([]string, 0)
+	for _, subscriber := range d.Subscribers {
+		if record[subscriber.Id] || d.IsSubscribers(subscriber.Id, timestamp) {
+			continue
+		}
+		record[subscriber.Id] = true
+		expired = append(expired, subscriber.Id)
+	}
+
+	d.RemoveSubscribers(expired)
+	for _, id := range expired {
+		if !d.IsCooperator(id) {
+			delete(d.IDNameMap, id)
+		}
+	}
+	return expired
+}
+
 func (d *Directory) RemoveSubscribers(id []string) {
 	record := make(map[string]bool)
 	remains := make([]*SubscriberMeta, 0)
@@ -262,6 +790,7 @@ func (d *Directory) AddFiles(fileMetas []*FileMeta) {
 	}
 
 	d.Files = append(d.Files, fileMetas...)
+	d.Usage += filesSize(fileMetas)
 }
 
 func (d *Directory) RemoveFiles(names []string) {
@@ -269,10 +798,12 @@ func (d *Directory) RemoveFiles(names []string) {
 	remains := make([]*FileMeta, 0)
 	for _, i := range names {
 		record[i] = true
+		delete(d.FileShares, i)
 	}
 
 	for _, i := range d.Files {
 		if record[i.Name] {
+			d.Usage -= i.Size
 			continue
 		}
 
@@ -280,6 +811,73 @@ func (d *Directory) RemoveFiles(names []string) {
 	}
 
 	d.Files = remains
+	if d.Usage < 0 {
+		d.Usage = 0
+	}
+}
+
+func (d *Directory) GetFile(name string) *FileMeta {
+	for _, file := range d.Files {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+//UpdateFile Replace the content and description of a file, keeping the usage of the directory in step.
+func (d *Directory) UpdateFile(name string, update *FileMeta, modifier string, timestamp int64) error {
+	file := d.GetFile(name)
+	if file == nil {
+		return fmt.Errorf("file doesn't exist")
+	}
+	d.Usage += update.Size - file.Size
+	file.Update(update, modifier, timestamp)
+	return nil
+}
+
+//ShareFile Give users or groups read access to a single file without access to the rest of the directory.
+func (d *Directory) ShareFile(name string, ids []string) error {
+	if d.GetFile(name) == nil {
+		return fmt.Errorf("file doesn't exist")
+	}
+	if d.FileShares == nil {
+		d.FileShares = make(map[string][]string)
+	}
+	for _, id := range ids {
+		if !d.IsFileSharedWith(name, id) {
+			d.FileShares[name] = append(d.FileShares[name], id)
+		}
+	}
+	return nil
+}
+
+func (d *Directory) UnshareFile(name string, ids []string) {
+	record := make(map[string]bool)
+	remains := make([]string, 0)
+	for _, i := range ids {
+		record[i] = true
+	}
+	for _, i := range d.FileShares[name] {
+		if record[i] {
+			continue
+		}
+		remains = append(remains, i)
+	}
+	if len(remains) == 0 {
+		delete(d.FileShares, name)
+		return
+	}
+	d.FileShares[name] = remains
+}
+
+func (d *Directory) IsFileSharedWith(name string, id string) bool {
+	for _, i := range d.FileShares[name] {
+		if i == id {
+			return true
+		}
+	}
+	return false
 }
 
 func (d *Directory) Save(ctx contractapi.TransactionContextInterface, key string) error {
@@ -293,8 +891,26 @@ func (d *Directory) Save(ctx contractapi.TransactionContextInterface, key string
 		return err
 	}
 	d.Date = timestamp.Seconds
+	previous := &Directory{Directories: make([]string, 0)}
+	if stored, err := getDirectory(ctx, key); err == nil {
+		previous = stored
+	}
+	if d.Usage > previous.Usage {
+		if err := d.CheckQuota(); err != nil {
+			return err
+		}
+	}
+	if err := chargeDirectoryUsage(ctx, previous, d); err != nil {
+		return err
+	}
 	if err := PutJsonState(ctx, key, d); err != nil {
 		return err
 	}
-	return nil
+	if err := updateParentIndex(ctx, key, previous.Directories, d.Directories); err != nil {
+		return err
+	}
+	if err := updateNameIndex(ctx, key, previous, d); err != nil {
+		return err
+	}
+	return updateOwnerIndex(ctx, key, d)
 }
@@ -93,3 +93,262 @@ func TestDirectory_IsCreator(t *testing.T) {
 		t.Errorf("should not be creator")
 	}
 }
+
+func TestDirectory_GetSubscriber(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	d.Subscribers = []*SubscriberMeta{{Id: "1", DueDate: 100}, {Id: "1", DueDate: 200}}
+	if meta := d.GetSubscriber("1"); meta == nil || meta.DueDate != 200 {
+		t.Errorf("should return the latest subscription")
+	}
+	if d.GetSubscriber("2") != nil {
+		t.Errorf("should not be subscriber")
+	}
+}
+
+func TestDirectory_SubscriptionDueDate(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	if date, _ := d.SubscriptionDueDate(100, 0, 0); date != 100+validity {
+		t.Errorf("should fall back to the default validity")
+	}
+	d.DefaultSubscriptionTerm = 50
+	d.MaxSubscriptionTerm = 60
+	if date, _ := d.SubscriptionDueDate(100, 0, 0); date != 150 {
+		t.Errorf("should use the default term of directory")
+	}
+	if date, _ := d.SubscriptionDueDate(100, 0, 130); date != 130 {
+		t.Errorf("should use the explicit due date")
+	}
+	if _, err := d.SubscriptionDueDate(100, 70, 0); err != subscriptionTermError {
+		t.Errorf("should reject terms exceeding the maximum")
+	}
+	d.DefaultSubscriptionTerm = 0
+	if date, _ := d.SubscriptionDueDate(100, 0, 0); date != 160 {
+		t.Errorf("default validity should be cut down to the maximum term")
+	}
+}
+
+func TestDirectory_RenewSubscribers(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	d.AddSubscribers([]string{"1", "2"}, []string{"1", "2"}, 200)
+	if err := d.RenewSubscribers([]string{"1", "3"}, 100, 150); err != nil {
+		t.Errorf("fail to renew subscription: %v", err)
+	}
+	if d.GetSubscriber("1").DueDate != 300 {
+		t.Errorf("should extend from the current due date")
+	}
+	if d.GetSubscriber("2").DueDate != 200 || d.GetSubscriber("3") != nil {
+		t.Errorf("should only renew the given subscribers")
+	}
+	if err := d.RenewSubscribers([]string{"2"}, 100, 250); err != nil || d.GetSubscriber("2").DueDate != 350 {
+		t.Errorf("should extend expired subscriptions from now")
+	}
+}
+
+func TestDirectory_PruneExpiredSubscribers(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	d.AddSubscribers([]string{"1", "2", "123"}, []string{"1", "2", "nmsl"}, 100)
+	d.AddSubscribers([]string{"2"}, []string{"2"}, 200)
+	pruned := d.PruneExpiredSubscribers(150)
+	if len(pruned) != 2 || d.GetSubscriber("1") != nil || d.GetSubscriber("2") == nil {
+		t.Errorf("should only prune expired subscribers")
+	}
+	if _, ok := d.IDNameMap["1"]; ok {
+		t.Errorf("should drop the name of pruned subscriber")
+	}
+	if _, ok := d.IDNameMap["123"]; !ok {
+		t.Errorf("should keep the name of cooperator")
+	}
+}
+
+func TestDirectory_CheckRemoval(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	d.AddCooperators([]string{"1", "2", "3"}, []string{"1", "2", "3"})
+	d.PromoteManagers([]string{"1", "4"})
+	if d.IsManager("4") {
+		t.Errorf("should only promote cooperators")
+	}
+	if err := d.CheckRemoval("1", []string{"123"}); err != creatorRemovalError {
+		t.Errorf("should protect the creator")
+	}
+	if err := d.CheckRemoval("2", []string{"3"}); err != rankError {
+		t.Errorf("cooperator should not remove peers")
+	}
+	if err := d.CheckRemoval("1", []string{"1", "2"}); err != nil {
+		t.Errorf("manager should remove itself and lower ranks: %v", err)
+	}
+	if err := d.CheckRemoval("123", []string{"1"}); err != nil {
+		t.Errorf("creator should remove managers: %v", err)
+	}
+
+	d.Creator = "0"
+	if err := d.CheckRemoval("1", []string{"1"}); err != lastManagerError {
+		t.Errorf("should keep the last manager")
+	}
+}
+
+func TestDirectory_IsActiveCooperator(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	d.AddCooperators([]string{"1", "2"}, []string{"1", "2"})
+	if err := d.SetCooperatorTerm([]string{"1"}, 100, 200); err != nil {
+		t.Errorf("fail to set term: %v", err)
+	}
+	if d.IsActiveCooperator("1", 50) || !d.IsActiveCooperator("1", 150) || d.IsActiveCooperator("1", 200) {
+		t.Errorf("should only be active within the term")
+	}
+	if !d.IsActiveCooperator("2", 300) || d.IsActiveCooperator("3", 150) {
+		t.Errorf("permanent cooperators should always be active")
+	}
+	if err := d.SetCooperatorTerm([]string{"123"}, 0, 200); err == nil {
+		t.Errorf("should not limit the creator")
+	}
+	d.SetCooperatorTerm([]string{"1"}, 0, 0)
+	if !d.IsActiveCooperator("1", 300) {
+		t.Errorf("should clear the term")
+	}
+}
+
+func TestDirectory_ShareFile(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	d.AddFiles([]*FileMeta{{Name: "a"}, {Name: "b"}})
+	if err := d.ShareFile("c", []string{"1"}); err == nil {
+		t.Errorf("should not share missing file")
+	}
+	d.ShareFile("a", []string{"1", "2"})
+	if !d.IsFileSharedWith("a", "1") || d.IsFileSharedWith("b", "1") {
+		t.Errorf("should only share the given file")
+	}
+	d.UnshareFile("a", []string{"1"})
+	if d.IsFileSharedWith("a", "1") || !d.IsFileSharedWith("a", "2") {
+		t.Errorf("fail to unshare file")
+	}
+	d.RemoveFiles([]string{"a"})
+	if _, ok := d.FileShares["a"]; ok {
+		t.Errorf("should drop the shares of removed file")
+	}
+}
+
+func TestValidateVisibility(t *testing.T) {
+	for _, visibility := range []string{Public, Private, Unlisted} {
+		if err := ValidateVisibility(visibility); err != nil {
+			t.Errorf("%s should be valid", visibility)
+		}
+	}
+	err := ValidateVisibility("public")
+	if visibilityError, ok := err.(*VisibilityError); !ok || visibilityError.Visibility != "public" {
+		t.Errorf("should report a VisibilityError")
+	}
+}
+
+func TestDirectory_IsReadableByKey(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Unlisted, 1231231)
+	if !d.IsReadableByKey() || d.IsListed() {
+		t.Errorf("unlisted directory should be readable but not listed")
+	}
+	d.Visibility = Private
+	if d.IsReadableByKey() || d.IsListed() {
+		t.Errorf("private directory should be neither readable nor listed")
+	}
+}
+
+func TestCalculateCopyKey(t *testing.T) {
+	keys := map[string]bool{
+		CalculateCopyKey("tx", "dest", "a", 0):  true,
+		CalculateCopyKey("tx", "dest", "a", 1):  true,
+		CalculateCopyKey("tx", "dest", "b", 0):  true,
+		CalculateCopyKey("tx", "other", "a", 0): true,
+	}
+	if len(keys) != 4 {
+		t.Errorf("copy keys should not collide")
+	}
+}
+
+func TestDirectory_CopyAccess(t *testing.T) {
+	source := NewDirectory("source", "123", "nmsl", Public, 1231231)
+	source.AddSubscribers([]string{"1"}, []string{"1"}, 100)
+	clone := NewDirectory("clone", "2", "2", Public, 1231231)
+	clone.CopyAccess(source)
+	if !clone.IsCooperator("123") || clone.GetSubscriber("1") == nil {
+		t.Errorf("fail to copy access lists")
+	}
+	clone.GetSubscriber("1").DueDate = 200
+	clone.Cooperators[0] = "3"
+	if source.GetSubscriber("1").DueDate != 100 || !source.IsCooperator("123") {
+		t.Errorf("copy should not share state with source")
+	}
+}
+
+func TestDirectory_Materialize(t *testing.T) {
+	target := NewDirectory("target", "123", "nmsl", Public, 1231231)
+	target.AddFiles([]*FileMeta{{Name: "a"}})
+	target.AddDirectories([]string{"child"})
+	mount := NewDirectory("mount", "2", "2", Private, 1231231)
+	mount.Target = "target"
+	mount.MountMode = CopyOnWriteMount
+	if !mount.IsMount() {
+		t.Errorf("directory with a target should be a mount")
+	}
+
+	mount.Materialize(target)
+	if mount.IsMount() || mount.GetFile("a") == nil || len(mount.Directories) != 1 {
+		t.Errorf("fail to materialize mount")
+	}
+	mount.RemoveFiles([]string{"a"})
+	mount.RemoveDirectories([]string{"child"})
+	if target.GetFile("a") == nil || len(target.Directories) != 1 {
+		t.Errorf("materialized mount should not share state with target")
+	}
+}
+
+func TestValidateMountMode(t *testing.T) {
+	if ValidateMountMode(ReadOnlyMount) != nil || ValidateMountMode(CopyOnWriteMount) != nil {
+		t.Errorf("valid mount modes are rejected")
+	}
+	if ValidateMountMode("ReadWrite") == nil {
+		t.Errorf("invalid mount mode is accepted")
+	}
+}
+
+func TestUnreachableDirectories(t *testing.T) {
+	order := []string{"root", "a", "b", "c", "d", "e", "f"}
+	parents := map[string][]string{
+		"root": {"top"},
+		"a":    {"root"},
+		"b":    {"root", "elsewhere"},
+		"c":    {"b"},
+		"d":    {"a", "c"},
+		"f":    {"e"},
+	}
+	unreachable := unreachableDirectories("root", order, parents)
+	if len(unreachable) != 2 || unreachable[0] != "root" || unreachable[1] != "a" {
+		t.Errorf("expect root and a to become unreachable, got %v", unreachable)
+	}
+}
+
+func TestCalculateDirectoryKey(t *testing.T) {
+	keys := map[string]bool{
+		CalculateDirectoryKey("tx", "123", "a", 0):  true,
+		CalculateDirectoryKey("tx", "123", "a", 1):  true,
+		CalculateDirectoryKey("tx2", "123", "a", 0): true,
+		CalculateDirectoryKey("tx", "123", "b", 0):  true,
+	}
+	if len(keys) != 4 {
+		t.Errorf("directory keys should not collide")
+	}
+}
+
+func TestDirectory_Usage(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	d.Quota = 10
+	d.AddFiles([]*FileMeta{{Name: "a", Size: 4}, {Name: "b", Size: 5}})
+	if d.Usage != 9 || d.CheckQuota() != nil {
+		t.Errorf("fail to track usage")
+	}
+	d.AddFiles([]*FileMeta{{Name: "c", Size: 2}})
+	if d.CheckQuota() != quotaError {
+		t.Errorf("should exceed quota")
+	}
+	d.RemoveFiles([]string{"a", "c"})
+	if d.Usage != 5 {
+		t.Errorf("fail to release usage of removed files")
+	}
+}
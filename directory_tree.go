@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// parentIndex maps a directory to the directories listing it as a child, so that a directory can be detached from its
+// parents and the reachability of a subtree can be decided without scanning the whole tree.
+const parentIndex = "parent"
+
+var protectedRootError = fmt.Errorf("can't delete the root directories of a user")
+
+// updateParentIndex synchronizes the parent index with the children of a directory, given its previous children.
+// Entries of all current children are written if missing, which also indexes trees saved before the index existed.
+func updateParentIndex(ctx contractapi.TransactionContextInterface, key string, previous []string, current []string) error {
+	remains := make(map[string]bool)
+	for _, child := range current {
+		remains[child] = true
+	}
+	for _, child := range previous {
+		if remains[child] {
+			continue
+		}
+		if err := putParentIndexEntry(ctx, child, key, false); err != nil {
+			return err
+		}
+	}
+	for _, child := range current {
+		if err := putParentIndexEntry(ctx, child, key, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putParentIndexEntry(ctx contractapi.TransactionContextInterface, child, parent string, present bool) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(parentIndex, []string{child, parent})
+	if err != nil {
+		return err
+	}
+	if !present {
+		return putIndexEntry(ctx, indexKey, nil, false)
+	}
+	bytes, err := ctx.GetStub().GetState(indexKey)
+	if err != nil {
+		return err
+	}
+	if len(bytes) > 0 {
+		return nil
+	}
+	return PutJsonState(ctx, indexKey, &SharedItem{Key: parent})
+}
+
+// listParents returns the keys of the directories listing key as a child.
+func listParents(ctx contractapi.TransactionContextInterface, key string) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(parentIndex, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	parents := make([]string, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		item := new(SharedItem)
+		if err = json.Unmarshal(kv.GetValue(), item); err != nil {
+			return nil, err
+		}
+		parents = append(parents, item.Key)
+	}
+	return parents, nil
+}
+
+// unreachableDirectories returns the directories of a subtree, in the given order, which can't be reached any more once
+// root is detached from its parents. A directory stays reachable while one of its parents is outside of the candidates.
+// A directory without indexed parents below root was listed by a parent saved before the index existed, whose other
+// references are unknown, so it stays reachable as well.
+func unreachableDirectories(root string, order []string, parents map[string][]string) []string {
+	candidates := make(map[string]bool)
+	for _, key := range order {
+		if key == root || len(parents[key]) > 0 {
+			candidates[key] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, key := range order {
+			if key == root || !candidates[key] {
+				continue
+			}
+			for _, parent := range parents[key] {
+				if !candidates[parent] {
+					delete(candidates, key)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	unreachable := make([]string, 0)
+	for _, key := range order {
+		if candidates[key] {
+			unreachable = append(unreachable, key)
+		}
+	}
+	return unreachable
+}
+
+// removeDirectoryIndexes removes the index entries which refer to a deleted directory.
+func removeDirectoryIndexes(ctx contractapi.TransactionContextInterface, key string, directory *Directory, parents []string) error {
+	if err := removeOwnerIndex(ctx, key, directory.Creator); err != nil {
+		return err
+	}
+	ids := append([]string{}, directory.Cooperators...)
+	for _, subscriber := range directory.Subscribers {
+		ids = append(ids, subscriber.Id)
+	}
+	if err := updateAccessIndex(ctx, key, &Directory{}, ids, false); err != nil {
+		return err
+	}
+	for name, ids := range directory.FileShares {
+		if err := updateFileShareIndex(ctx, key, &Directory{}, name, ids); err != nil {
+			return err
+		}
+	}
+	if err := updateNameIndex(ctx, key, directory, &Directory{}); err != nil {
+		return err
+	}
+	if err := updateTagIndex(ctx, key, &Directory{}, "", directory.Tags); err != nil {
+		return err
+	}
+	for _, file := range directory.Files {
+		if err := updateTagIndex(ctx, key, &Directory{}, file.Name, file.Tags); err != nil {
+			return err
+		}
+	}
+	if err := removePendingRequests(ctx, key); err != nil {
+		return err
+	}
+	for _, parent := range parents {
+		if err := putParentIndexEntry(ctx, key, parent, false); err != nil {
+			return err
+		}
+	}
+	for _, child := range directory.Directories {
+		if err := putParentIndexEntry(ctx, child, key, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removePendingRequests removes the invitations, share links, subscription requests and ownership transfer of a
+// deleted directory.
+func removePendingRequests(ctx contractapi.TransactionContextInterface, key string) error {
+	invitations, err := listInvitations(ctx, directoryInvitationIndex, key, 0)
+	if err != nil {
+		return err
+	}
+	for _, invitation := range invitations {
+		if err = deleteInvitation(ctx, key, invitation.Invitee); err != nil {
+			return err
+		}
+	}
+
+	linkIDs := make([]string, 0)
+	requestKeys := make([]string, 0)
+	for _, index := range []string{directoryShareLinkIndex, subscriptionRequestIndex} {
+		iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{key})
+		if err != nil {
+			return err
+		}
+		for iterator.HasNext() {
+			kv, err := iterator.Next()
+			if err != nil {
+				iterator.Close()
+				return err
+			}
+			if index == subscriptionRequestIndex {
+				requestKeys = append(requestKeys, kv.GetKey())
+				continue
+			}
+			var linkID string
+			if err = json.Unmarshal(kv.GetValue(), &linkID); err != nil {
+				iterator.Close()
+				return err
+			}
+			linkIDs = append(linkIDs, linkID)
+		}
+		iterator.Close()
+	}
+	for _, linkID := range linkIDs {
+		if err = deleteShareLink(ctx, &ShareLink{Key: key, Id: linkID}); err != nil {
+			return err
+		}
+	}
+	for _, requestKey := range requestKeys {
+		if err = ctx.GetStub().DelState(requestKey); err != nil {
+			return err
+		}
+	}
+
+	transferKey, err := ctx.GetStub().CreateCompositeKey(ownershipTransferIndex, []string{key})
+	if err != nil {
+		return err
+	}
+	return putIndexEntry(ctx, transferKey, nil, false)
+}
@@ -0,0 +1,98 @@
+package main
+
+import "testing"
+
+func TestDeleteDirectory_ProtectsCreatorRoots(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	profile, err := getUserProfile(l.as(alice), alice.ID())
+	l.must(err)
+
+	l.must(l.contract.AddCooperators(l.as(alice), profile.Share, []string{bob.ID()}, false))
+	l.must(l.contract.PromoteManagers(l.as(alice), profile.Share, []string{bob.ID()}, false))
+	if _, err = l.contract.DeleteDirectory(l.as(bob), profile.Share, false, false); err != protectedRootError {
+		t.Errorf("a manager should not delete a root directory of the creator, got %v", err)
+	}
+}
+
+func TestDeleteDirectory_RequiresManagerRank(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	key := l.directory(alice, "reports", Private)
+
+	l.must(l.contract.AddCooperators(l.as(alice), key, []string{bob.ID()}, false))
+	if _, err := l.contract.DeleteDirectory(l.as(bob), key, false, false); err != privilegeError {
+		t.Errorf("a plain cooperator should not delete a directory of another user, got %v", err)
+	}
+	l.must(l.contract.PromoteManagers(l.as(alice), key, []string{bob.ID()}, false))
+	deleted, err := l.contract.DeleteDirectory(l.as(bob), key, false, false)
+	l.must(err)
+	if len(deleted) != 1 || deleted[0] != key {
+		t.Errorf("a manager should delete the directory, got %v", deleted)
+	}
+}
+
+func TestDeleteDirectory_KeepsChildrenOfUnindexedParents(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	parent := l.directory(alice, "parent", Private)
+	child := l.directory(alice, "child", Private)
+	_, err := l.contract.AddDirectories(l.as(alice), parent, []string{child})
+	l.must(err)
+
+	// Drop the index entry, as if the parent had been saved before the index existed.
+	ctx := l.as(alice)
+	indexKey, err := ctx.GetStub().CreateCompositeKey(parentIndex, []string{child, parent})
+	l.must(err)
+	l.must(ctx.GetStub().DelState(indexKey))
+
+	deleted, err := l.contract.DeleteDirectory(l.as(alice), parent, true, true)
+	l.must(err)
+	if len(deleted) != 1 || deleted[0] != parent {
+		t.Errorf("a child without indexed parents should be kept, got %v", deleted)
+	}
+
+	l.must(l.contract.ReindexParents(l.as(alice), []string{parent}))
+	deleted, err = l.contract.DeleteDirectory(l.as(alice), parent, true, true)
+	l.must(err)
+	if len(deleted) != 2 {
+		t.Errorf("a reindexed child should be deleted with its only parent, got %v", deleted)
+	}
+}
+
+func TestDeleteDirectory_RemovesPendingRequests(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol, dave := l.user("alice"), l.user("bob"), l.user("carol"), l.user("dave")
+	key := l.directory(alice, "reports", Private)
+
+	_, err := l.contract.InviteCooperator(l.as(alice), key, bob.ID(), 0, false)
+	l.must(err)
+	_, err = l.contract.CreateShareLink(l.withSecret(alice, testSecret), key, SubscriberRole, 0, 0)
+	l.must(err)
+	l.must(l.contract.RequestSubscription(l.as(carol), key, "please"))
+	l.must(l.contract.TransferOwnership(l.as(alice), key, dave.ID(), false, true))
+
+	_, err = l.contract.DeleteDirectory(l.as(alice), key, false, false)
+	l.must(err)
+
+	// Check the ledger first, the mock stub keeps the writes of failing transactions.
+	for _, index := range []string{directoryInvitationIndex, directoryShareLinkIndex, subscriptionRequestIndex, ownershipTransferIndex} {
+		iterator, err := l.as(alice).GetStub().GetStateByPartialCompositeKey(index, []string{key})
+		l.must(err)
+		if iterator.HasNext() {
+			t.Errorf("%s entries of a deleted directory should be removed", index)
+		}
+		iterator.Close()
+	}
+	invitations, err := l.contract.ListInvitations(l.as(bob))
+	l.must(err)
+	if len(invitations) != 0 {
+		t.Errorf("invitations to a deleted directory should be removed, got %v", invitations)
+	}
+	if _, err = l.contract.RedeemShareLink(l.withSecret(carol, testSecret)); err == nil {
+		t.Errorf("a share link of a deleted directory should not be redeemable")
+	}
+	if err = l.contract.AcceptOwnership(l.as(dave), key); err == nil {
+		t.Errorf("a transfer of a deleted directory should not be accepted")
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+// ExpiringGrant describes an access grant of a directory which is about to expire.
+type ExpiringGrant struct {
+	Key           string `json:"key"`
+	DirectoryName string `json:"directoryName"`
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	Role          string `json:"role"`
+	DueDate       int64  `json:"dueDate"`
+}
@@ -1,8 +1,90 @@
 package main
 
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+const (
+	maxDescriptionLength = 1024
+	maxAttributes        = 32
+	maxAttributeLength   = 256
+)
+
+var mimeTypePattern = regexp.MustCompile(`^[\w.+-]+/[\w.+-]+$`)
+
+// FileMeta describes a file stored off chain. CreateDate, ModifyDate, Uploader and Modifier are filled in by the
+// chaincode, the other fields are supplied by the client.
 type FileMeta struct {
-	Cid        string `json:"cid"`
-	CreateDate int64  `json:"createDate"`
-	Name       string `json:"name"`
-	Key        string `json:"key"`
+	Cid         string            `json:"cid"`
+	CreateDate  int64             `json:"createDate"`
+	Name        string            `json:"name"`
+	Key         string            `json:"key"`
+	Size        int64             `json:"size"`
+	MimeType    string            `json:"mimeType"`
+	Hash        string            `json:"hash"`
+	Uploader    string            `json:"uploader"`
+	Modifier    string            `json:"modifier"`
+	ModifyDate  int64             `json:"modifyDate"`
+	Description string            `json:"description"`
+	Attributes  map[string]string `json:"attributes"`
+	Tags        []string          `json:"tags"`
+}
+
+//Validate Check the fields supplied by the client.
+func (f *FileMeta) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("file name can't be empty")
+	}
+	if f.Size < 0 {
+		return fmt.Errorf("size of file %s can't be negative", f.Name)
+	}
+	if f.MimeType != "" && !mimeTypePattern.MatchString(f.MimeType) {
+		return fmt.Errorf("invalid mime type %q of file %s", f.MimeType, f.Name)
+	}
+	if f.Hash != "" {
+		if _, err := hex.DecodeString(f.Hash); err != nil {
+			return fmt.Errorf("hash of file %s must be hex encoded", f.Name)
+		}
+	}
+	if len(f.Description) > maxDescriptionLength {
+		return fmt.Errorf("description of file %s exceeds %d bytes", f.Name, maxDescriptionLength)
+	}
+	if len(f.Attributes) > maxAttributes {
+		return fmt.Errorf("file %s has more than %d attributes", f.Name, maxAttributes)
+	}
+	for key, value := range f.Attributes {
+		if key == "" {
+			return fmt.Errorf("attribute name of file %s can't be empty", f.Name)
+		}
+		if len(key) > maxAttributeLength || len(value) > maxAttributeLength {
+			return fmt.Errorf("attribute %s of file %s exceeds %d bytes", key, f.Name, maxAttributeLength)
+		}
+	}
+	if err := validateTags(f.Tags); err != nil {
+		return fmt.Errorf("invalid tags of file %s: %v", f.Name, err)
+	}
+	return nil
+}
+
+//Stamp Fill in the trusted fields of a file added by uploader at timestamp.
+func (f *FileMeta) Stamp(uploader string, timestamp int64) {
+	f.CreateDate = timestamp
+	f.Uploader = uploader
+	f.ModifyDate = timestamp
+	f.Modifier = uploader
+}
+
+//Update Replace the content and description of the file with those of update. The name, the key, the tags and the
+//upload are kept.
+func (f *FileMeta) Update(update *FileMeta, modifier string, timestamp int64) {
+	f.Cid = update.Cid
+	f.Size = update.Size
+	f.MimeType = update.MimeType
+	f.Hash = update.Hash
+	f.Description = update.Description
+	f.Attributes = update.Attributes
+	f.Modifier = modifier
+	f.ModifyDate = timestamp
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileMeta_Validate(t *testing.T) {
+	file := &FileMeta{
+		Name:       "a.txt",
+		Size:       3,
+		MimeType:   "text/plain",
+		Hash:       "ba7816bf",
+		Attributes: map[string]string{"lang": "en"},
+	}
+	if err := file.Validate(); err != nil {
+		t.Errorf("valid file is rejected: %v", err)
+	}
+
+	invalid := []*FileMeta{
+		{},
+		{Name: "a", Size: -1},
+		{Name: "a", MimeType: "text"},
+		{Name: "a", Hash: "xyz"},
+		{Name: "a", Description: strings.Repeat("a", maxDescriptionLength+1)},
+		{Name: "a", Attributes: map[string]string{"": "v"}},
+	}
+	for _, file := range invalid {
+		if file.Validate() == nil {
+			t.Errorf("invalid file is accepted: %+v", file)
+		}
+	}
+}
+
+func TestDirectory_UpdateFile(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	file := &FileMeta{Name: "a", Size: 4, Cid: "old"}
+	file.Stamp("123", 100)
+	d.AddFiles([]*FileMeta{file})
+
+	if err := d.UpdateFile("a", &FileMeta{Cid: "new", Size: 10}, "456", 200); err != nil {
+		t.Errorf("fail to update file: %v", err)
+	}
+	updated := d.GetFile("a")
+	if updated.Cid != "new" || updated.Uploader != "123" || updated.CreateDate != 100 ||
+		updated.Modifier != "456" || updated.ModifyDate != 200 || d.Usage != 10 {
+		t.Errorf("unexpected file after update: %+v", updated)
+	}
+	if d.UpdateFile("b", &FileMeta{}, "456", 200) == nil {
+		t.Errorf("updating a missing file should fail")
+	}
+}
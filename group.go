@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"strings"
+)
+
+const (
+	groupIndex       = "group"
+	groupMemberIndex = "groupMember"
+)
+
+// GroupPrefix marks ids of access lists which refer to a group instead of a single user.
+const GroupPrefix = "group:"
+
+type Group struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Owner   string   `json:"owner"`
+	Members []string `json:"members"`
+	Date    int64    `json:"date"`
+}
+
+func IsGroupReference(id string) bool {
+	return strings.HasPrefix(id, GroupPrefix)
+}
+
+func (g *Group) Reference() string {
+	return GroupPrefix + g.Id
+}
+
+func (g *Group) IsMember(id string) bool {
+	for _, member := range g.Members {
+		if member == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Group) AddMembers(ids []string) {
+	for _, id := range ids {
+		if !g.IsMember(id) {
+			g.Members = append(g.Members, id)
+		}
+	}
+}
+
+func (g *Group) RemoveMembers(ids []string) {
+	record := make(map[string]bool)
+	remains := make([]string, 0)
+	for _, i := range ids {
+		record[i] = true
+	}
+	for _, i := range g.Members {
+		if record[i] {
+			continue
+		}
+		remains = append(remains, i)
+	}
+	g.Members = remains
+}
+
+func groupKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(groupIndex, []string{strings.TrimPrefix(id, GroupPrefix)})
+}
+
+// getGroup loads a group by its id or its reference.
+func getGroup(ctx contractapi.TransactionContextInterface, id string) (*Group, error) {
+	key, err := groupKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	group := new(Group)
+	if err = GetJsonState(ctx, key, group); err != nil {
+		return nil, fmt.Errorf("group doesn't exist")
+	}
+	return group, nil
+}
+
+func (g *Group) Save(ctx contractapi.TransactionContextInterface) error {
+	key, err := groupKey(ctx, g.Id)
+	if err != nil {
+		return err
+	}
+	return PutJsonState(ctx, key, g)
+}
+
+func updateGroupMemberIndex(ctx contractapi.TransactionContextInterface, group *Group, ids []string) error {
+	for _, id := range ids {
+		memberKey, err := ctx.GetStub().CreateCompositeKey(groupMemberIndex, []string{id, group.Id})
+		if err != nil {
+			return err
+		}
+		if !group.IsMember(id) {
+			if err = ctx.GetStub().DelState(memberKey); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = PutJsonState(ctx, memberKey, group.Reference()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPrincipals returns the id of the user followed by the references of all groups the user is a member of.
+func getPrincipals(ctx contractapi.TransactionContextInterface, id string) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(groupMemberIndex, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	principals := []string{id}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var reference string
+		if err = json.Unmarshal(kv.GetValue(), &reference); err != nil {
+			return nil, err
+		}
+		principals = append(principals, reference)
+	}
+	return principals, nil
+}
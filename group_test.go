@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestGroup_Members(t *testing.T) {
+	group := &Group{Id: "g", Owner: "1", Members: []string{"1"}}
+	group.AddMembers([]string{"2", "3", "2"})
+	if len(group.Members) != 3 || !group.IsMember("2") {
+		t.Errorf("fail to add members")
+	}
+	group.RemoveMembers([]string{"2"})
+	if group.IsMember("2") || !group.IsMember("3") {
+		t.Errorf("fail to remove members")
+	}
+	if group.Reference() != "group:g" || !IsGroupReference(group.Reference()) || IsGroupReference("g") {
+		t.Errorf("wrong group reference")
+	}
+}
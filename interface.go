@@ -6,22 +6,85 @@ type BlockDriveInterface interface {
 	InitiateUserProfile(ctx contractapi.TransactionContextInterface, name string) (*UserProfile, error)
 	ReadUserProfile(ctx contractapi.TransactionContextInterface) (*UserProfile, error)
 
-	CreateDirectory(ctx contractapi.TransactionContextInterface, name string, visibility string) (string, error)
+	CreateDirectory(ctx contractapi.TransactionContextInterface, name string, visibility string, parentKey string, inheritAccess bool) (string, error)
 	ReadDirectory(ctx contractapi.TransactionContextInterface, keys string) (*Directory, error)
 	ReadDirectories(ctx contractapi.TransactionContextInterface, keys []string) (map[string]*Directory, error)
 	AddDirectories(ctx contractapi.TransactionContextInterface, parentKey string, childrenKeys []string) (*Directory, error)
 	RemoveDirectories(ctx contractapi.TransactionContextInterface, parentKey string, childrenKeys []string) (*Directory, error)
+	DeleteDirectory(ctx contractapi.TransactionContextInterface, key string, recursive bool, reportOnly bool) ([]string, error)
+	ReindexParents(ctx contractapi.TransactionContextInterface, keys []string) error
 	RenameDirectory(ctx contractapi.TransactionContextInterface, keys string, name string) (*Directory, error)
 	AddFile(ctx contractapi.TransactionContextInterface, key string, files []*FileMeta) (*Directory, error)
+	UpdateFile(ctx contractapi.TransactionContextInterface, key string, fileName string, update *FileMeta) (*Directory, error)
 	RemoveFile(ctx contractapi.TransactionContextInterface, key string, file []string) (*Directory, error)
+	ShareFile(ctx contractapi.TransactionContextInterface, key string, fileName string, ids []string) (*Directory, error)
+	UnshareFile(ctx contractapi.TransactionContextInterface, key string, fileName string, ids []string) (*Directory, error)
+	ReadSharedFile(ctx contractapi.TransactionContextInterface, key string, fileName string) (*FileMeta, error)
 	SetDirectoryVisibility(ctx contractapi.TransactionContextInterface, key string, visibility string) (*Directory, error)
 	ReadDirectoryHistory(ctx contractapi.TransactionContextInterface, key string) ([]*Directory, error)
-	CopyDirectory(ctx contractapi.TransactionContextInterface, source, destination string) error
+	CopyDirectory(ctx contractapi.TransactionContextInterface, source, destination string) (map[string]string, error)
+	CopyDirectoryWithOptions(ctx contractapi.TransactionContextInterface, source, destination string, options CopyOptions) (map[string]string, error)
+	CreateMount(ctx contractapi.TransactionContextInterface, parentKey string, targetKey string, name string, mode string) (string, error)
+	TransferOwnership(ctx contractapi.TransactionContextInterface, key string, newOwnerID string, recursive bool, requireAcceptance bool) error
+	AcceptOwnership(ctx contractapi.TransactionContextInterface, key string) error
+	DeclineOwnership(ctx contractapi.TransactionContextInterface, key string) error
 
 	AddSubscribers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error
+	AddSubscribersWithTerm(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool, duration int64, dueDate int64) error
+	RenewSubscription(ctx contractapi.TransactionContextInterface, key string, ids []string, duration int64, recursive bool) error
+	SetSubscriptionTerms(ctx contractapi.TransactionContextInterface, key string, defaultTerm int64, maxTerm int64) (*Directory, error)
 	AddCooperators(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error
+	AddCooperatorsWithTerm(ctx contractapi.TransactionContextInterface, key string, ids []string, startDate int64, dueDate int64, recursive bool) error
+	ExpiringCooperatorGrants(ctx contractapi.TransactionContextInterface, withinSeconds int64) ([]*ExpiringGrant, error)
 	RemoveSubscribers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error
 	RemoveCooperators(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error
+	InviteCooperator(ctx contractapi.TransactionContextInterface, key string, inviteeID string, validFor int64, recursive bool) (*Invitation, error)
+	AcceptInvitation(ctx contractapi.TransactionContextInterface, key string) (*Directory, error)
+	DeclineInvitation(ctx contractapi.TransactionContextInterface, key string) error
+	RevokeInvitation(ctx contractapi.TransactionContextInterface, key string, inviteeID string) error
+	ListInvitations(ctx contractapi.TransactionContextInterface) ([]*Invitation, error)
+	ListDirectoryInvitations(ctx contractapi.TransactionContextInterface, key string) ([]*Invitation, error)
+	PromoteManagers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error
+	DemoteManagers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error
+	GrantOrganization(ctx contractapi.TransactionContextInterface, key string, mspID string, role string, attributes map[string]string, recursive bool) error
+	RevokeOrganization(ctx contractapi.TransactionContextInterface, key string, mspID string, recursive bool) error
+	SetAccessPolicy(ctx contractapi.TransactionContextInterface, key string, role string, mspID string, expression string, recursive bool) error
+	CreateShareLink(ctx contractapi.TransactionContextInterface, key string, role string, expiry int64, maxUses int64) (*ShareLink, error)
+	RedeemShareLink(ctx contractapi.TransactionContextInterface) (*Directory, error)
+	RevokeShareLink(ctx contractapi.TransactionContextInterface, key string, linkID string) error
+	ListShareLinks(ctx contractapi.TransactionContextInterface, key string) ([]*ShareLink, error)
+	PruneExpiredSubscribers(ctx contractapi.TransactionContextInterface, key string, recursive bool) ([]string, error)
+	ExpiringSubscriptions(ctx contractapi.TransactionContextInterface, withinSeconds int64) ([]*ExpiringGrant, error)
 
 	Subscribe(ctx contractapi.TransactionContextInterface, key string) (*Directory, error)
+	RequestSubscription(ctx contractapi.TransactionContextInterface, key string, message string) error
+	ListPendingRequests(ctx contractapi.TransactionContextInterface, key string) ([]*SubscriptionRequest, error)
+	ApproveSubscription(ctx contractapi.TransactionContextInterface, key string, requester string) error
+	RejectSubscription(ctx contractapi.TransactionContextInterface, key string, requester string) error
+
+	Mint(ctx contractapi.TransactionContextInterface, userID string, amount int64) (*UserProfile, error)
+	Transfer(ctx contractapi.TransactionContextInterface, to string, amount int64) (*UserProfile, error)
+	SetSubscriptionPrice(ctx contractapi.TransactionContextInterface, key string, price int64, period int64) (*Directory, error)
+	PurchaseSubscription(ctx contractapi.TransactionContextInterface, key string) (*Directory, error)
+
+	CreateGroup(ctx contractapi.TransactionContextInterface, name string, members []string) (*Group, error)
+	ReadGroup(ctx contractapi.TransactionContextInterface, groupID string) (*Group, error)
+	AddGroupMembers(ctx contractapi.TransactionContextInterface, groupID string, ids []string) (*Group, error)
+	RemoveGroupMembers(ctx contractapi.TransactionContextInterface, groupID string, ids []string) (*Group, error)
+
+	ListSharedWithMe(ctx contractapi.TransactionContextInterface) ([]*SharedItem, error)
+	ListSubscriptions(ctx contractapi.TransactionContextInterface) ([]*SharedItem, error)
+
+	SetUserQuota(ctx contractapi.TransactionContextInterface, userID string, quota int64) (*Usage, error)
+	SetDirectoryQuota(ctx contractapi.TransactionContextInterface, key string, quota int64) (*Usage, error)
+	GetUsage(ctx contractapi.TransactionContextInterface, target string) (*Usage, error)
+
+	AddTags(ctx contractapi.TransactionContextInterface, key string, fileName string, tags []string) (*Directory, error)
+	RemoveTags(ctx contractapi.TransactionContextInterface, key string, fileName string, tags []string) (*Directory, error)
+	FindByTag(ctx contractapi.TransactionContextInterface, tag string) ([]*TaggedItem, error)
+
+	SearchByName(ctx contractapi.TransactionContextInterface, query string, rootKey string, limit int, prefixOnly bool) (*SearchResults, error)
+	ReindexNames(ctx contractapi.TransactionContextInterface, keys []string) error
+
+	ExecuteBatch(ctx contractapi.TransactionContextInterface, ops []*BatchOperation) ([]*BatchResult, error)
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	invitationIndex          = "invitation"
+	directoryInvitationIndex = "directoryInvitation"
+)
+
+const invitationValidity = 604800
+
+// Invitation is a pending offer to become a cooperator of a directory. It is stored under the invitee and under the
+// directory so both sides can list it.
+type Invitation struct {
+	Key           string `json:"key"`
+	DirectoryName string `json:"directoryName"`
+	Inviter       string `json:"inviter"`
+	InviterName   string `json:"inviterName"`
+	Invitee       string `json:"invitee"`
+	Recursive     bool   `json:"recursive"`
+	Date          int64  `json:"date"`
+	DueDate       int64  `json:"dueDate"`
+}
+
+func invitationKeys(ctx contractapi.TransactionContextInterface, key, invitee string) ([]string, error) {
+	inviteeKey, err := ctx.GetStub().CreateCompositeKey(invitationIndex, []string{invitee, key})
+	if err != nil {
+		return nil, err
+	}
+	directoryKey, err := ctx.GetStub().CreateCompositeKey(directoryInvitationIndex, []string{key, invitee})
+	if err != nil {
+		return nil, err
+	}
+	return []string{inviteeKey, directoryKey}, nil
+}
+
+func putInvitation(ctx contractapi.TransactionContextInterface, invitation *Invitation) error {
+	keys, err := invitationKeys(ctx, invitation.Key, invitation.Invitee)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err = PutJsonState(ctx, key, invitation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getInvitation(ctx contractapi.TransactionContextInterface, key, invitee string) (*Invitation, error) {
+	keys, err := invitationKeys(ctx, key, invitee)
+	if err != nil {
+		return nil, err
+	}
+	invitation := new(Invitation)
+	if err = GetJsonState(ctx, keys[0], invitation); err != nil {
+		return nil, fmt.Errorf("invitation doesn't exist")
+	}
+	return invitation, nil
+}
+
+func deleteInvitation(ctx contractapi.TransactionContextInterface, key, invitee string) error {
+	keys, err := invitationKeys(ctx, key, invitee)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err = ctx.GetStub().DelState(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listInvitations returns the invitations of an index which are still valid at timestamp.
+func listInvitations(ctx contractapi.TransactionContextInterface, index, attribute string, timestamp int64) ([]*Invitation, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{attribute})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	invitations := make([]*Invitation, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		invitation := new(Invitation)
+		if err = json.Unmarshal(kv.GetValue(), invitation); err != nil {
+			return nil, err
+		}
+		if invitation.DueDate <= timestamp {
+			continue
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import "testing"
+
+func TestAcceptInvitation_SkipsForeignChildren(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol, dave := l.user("alice"), l.user("bob"), l.user("carol"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+	victim := l.directory(carol, "victim", Private)
+
+	l.must(l.contract.AddCooperators(l.as(alice), parent, []string{bob.ID()}, false))
+	_, err := l.contract.AddDirectories(l.as(bob), parent, []string{victim})
+	l.must(err)
+	_, err = l.contract.InviteCooperator(l.as(bob), parent, dave.ID(), 0, true)
+	l.must(err)
+	_, err = l.contract.AcceptInvitation(l.as(dave), parent)
+	l.must(err)
+
+	if !l.read(parent).IsCooperator(dave.ID()) {
+		t.Errorf("invitee should cooperate on the directory of the invitation")
+	}
+	if l.read(victim).IsCooperator(dave.ID()) {
+		t.Errorf("invitee should not gain access to a child the inviter has no rights on")
+	}
+}
+
+func TestAcceptInvitation_InviterTermExpired(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, dave := l.user("alice"), l.user("bob"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), parent, []string{bob.ID()}, 0, l.now+100, false))
+	_, err := l.contract.InviteCooperator(l.as(bob), parent, dave.ID(), 0, false)
+	l.must(err)
+
+	l.now += 200
+	if _, err = l.contract.AcceptInvitation(l.as(dave), parent); err == nil {
+		t.Errorf("invitation of a cooperator whose term expired should not be accepted")
+	}
+}
+
+func TestAcceptInvitation_InviterThroughGroup(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, dave := l.user("alice"), l.user("bob"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+
+	group, err := l.contract.CreateGroup(l.as(alice), "team", []string{bob.ID()})
+	l.must(err)
+	l.must(l.contract.AddCooperators(l.as(alice), parent, []string{group.Reference()}, false))
+	_, err = l.contract.InviteCooperator(l.as(bob), parent, dave.ID(), 0, false)
+	l.must(err)
+	_, err = l.contract.AcceptInvitation(l.as(dave), parent)
+	l.must(err)
+	if !l.read(parent).IsCooperator(dave.ID()) {
+		t.Errorf("invitation of a cooperator through a group should be accepted")
+	}
+}
+
+func TestAcceptInvitation_Expired(t *testing.T) {
+	l := newTestLedger(t)
+	alice, dave := l.user("alice"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+
+	invitation, err := l.contract.InviteCooperator(l.as(alice), parent, dave.ID(), 100, false)
+	l.must(err)
+	if invitation.DueDate != l.now+100 {
+		t.Errorf("invitation should expire after its validity, got %d", invitation.DueDate)
+	}
+	invitations, err := l.contract.ListInvitations(l.as(dave))
+	l.must(err)
+	if len(invitations) != 1 || invitations[0].Key != parent {
+		t.Errorf("invitee should see the pending invitation, got %v", invitations)
+	}
+
+	l.now += 200
+	if invitations, _ = l.contract.ListInvitations(l.as(dave)); len(invitations) != 0 {
+		t.Errorf("expired invitation should not be listed")
+	}
+	if _, err = l.contract.AcceptInvitation(l.as(dave), parent); err == nil {
+		t.Errorf("expired invitation should not be accepted")
+	}
+	if l.read(parent).IsCooperator(dave.ID()) {
+		t.Errorf("expired invitation should not grant access")
+	}
+}
+
+func TestAcceptInvitation_OnlyInvitee(t *testing.T) {
+	l := newTestLedger(t)
+	alice, carol, dave := l.user("alice"), l.user("carol"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+
+	_, err := l.contract.InviteCooperator(l.as(alice), parent, dave.ID(), 0, false)
+	l.must(err)
+	if _, err = l.contract.AcceptInvitation(l.as(carol), parent); err == nil {
+		t.Errorf("invitation should only be accepted by its invitee")
+	}
+	_, err = l.contract.AcceptInvitation(l.as(dave), parent)
+	l.must(err)
+	if _, err = l.contract.AcceptInvitation(l.as(dave), parent); err == nil {
+		t.Errorf("accepted invitation should not be accepted again")
+	}
+}
+
+func TestDeclineInvitation(t *testing.T) {
+	l := newTestLedger(t)
+	alice, dave := l.user("alice"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+
+	_, err := l.contract.InviteCooperator(l.as(alice), parent, dave.ID(), 0, false)
+	l.must(err)
+	l.must(l.contract.DeclineInvitation(l.as(dave), parent))
+	if _, err = l.contract.AcceptInvitation(l.as(dave), parent); err == nil {
+		t.Errorf("declined invitation should not be accepted")
+	}
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	l := newTestLedger(t)
+	alice, carol, dave := l.user("alice"), l.user("carol"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+
+	_, err := l.contract.InviteCooperator(l.as(alice), parent, dave.ID(), 0, false)
+	l.must(err)
+	if err = l.contract.RevokeInvitation(l.as(carol), parent, dave.ID()); err != privilegeError {
+		t.Errorf("only cooperators should revoke invitations, got %v", err)
+	}
+	l.must(l.contract.RevokeInvitation(l.as(alice), parent, dave.ID()))
+	if _, err = l.contract.AcceptInvitation(l.as(dave), parent); err == nil {
+		t.Errorf("revoked invitation should not be accepted")
+	}
+}
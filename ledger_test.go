@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"os"
+	"testing"
+)
+
+const testAdminMSP = "AdminMSP"
+
+// testIdentity is a client identity whose certificate only carries its name.
+type testIdentity struct {
+	name       string
+	mspID      string
+	attributes map[string]string
+}
+
+func newTestIdentity(name string) *testIdentity {
+	return &testIdentity{name: name, mspID: "Org2MSP", attributes: make(map[string]string)}
+}
+
+// newTestAdmin returns an identity carrying the admin attribute in the admin organization.
+func newTestAdmin(name string) *testIdentity {
+	identity := newTestIdentity(name)
+	identity.mspID = testAdminMSP
+	identity.attributes[AdminAttribute] = "true"
+	return identity
+}
+
+func (i *testIdentity) ID() string {
+	return SHA256Bytes([]byte(i.name))[0:8]
+}
+
+func (i *testIdentity) GetID() (string, error) {
+	return i.name, nil
+}
+
+func (i *testIdentity) GetMSPID() (string, error) {
+	return i.mspID, nil
+}
+
+func (i *testIdentity) GetAttributeValue(name string) (string, bool, error) {
+	value, ok := i.attributes[name]
+	return value, ok, nil
+}
+
+func (i *testIdentity) AssertAttributeValue(name, value string) error {
+	if i.attributes[name] != value {
+		return fmt.Errorf("attribute %s doesn't match", name)
+	}
+	return nil
+}
+
+func (i *testIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{Raw: []byte(i.name)}, nil
+}
+
+// testLedger runs transactions of the contract against a mock stub. Every call of as starts a new transaction at the
+// current time of the ledger.
+type testLedger struct {
+	t        *testing.T
+	contract *SmartContract
+	stub     *shimtest.MockStub
+	now      int64
+	tx       int
+}
+
+func newTestLedger(t *testing.T) *testLedger {
+	if err := os.Setenv(AdminMSPVariable, testAdminMSP); err != nil {
+		t.Fatal(err)
+	}
+	return &testLedger{t: t, contract: new(SmartContract), stub: shimtest.NewMockStub("fabric-fs", nil), now: 1600000000}
+}
+
+func (l *testLedger) as(identity *testIdentity) contractapi.TransactionContextInterface {
+	l.tx++
+	l.stub.MockTransactionStart(fmt.Sprintf("tx%d", l.tx))
+	l.stub.TxTimestamp = &timestamp.Timestamp{Seconds: l.now}
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(l.stub)
+	ctx.SetClientIdentity(identity)
+	return ctx
+}
+
+// user creates the profile of a new user.
+func (l *testLedger) user(name string) *testIdentity {
+	identity := newTestIdentity(name)
+	if _, err := l.contract.InitiateUserProfile(l.as(identity), name); err != nil {
+		l.t.Fatalf("fail to initiate profile of %s: %v", name, err)
+	}
+	return identity
+}
+
+// directory creates a directory owned by identity.
+func (l *testLedger) directory(identity *testIdentity, name string, visibility string) string {
+	key, err := l.contract.CreateDirectory(l.as(identity), name, visibility, "", false)
+	if err != nil {
+		l.t.Fatalf("fail to create directory %s: %v", name, err)
+	}
+	return key
+}
+
+func (l *testLedger) read(key string) *Directory {
+	directory, err := getDirectory(l.as(newTestIdentity("reader")), key)
+	if err != nil {
+		l.t.Fatalf("fail to read directory %s: %v", key, err)
+	}
+	return directory
+}
+
+func (l *testLedger) must(err error) {
+	l.t.Helper()
+	if err != nil {
+		l.t.Fatalf("unexpected error: %v", err)
+	}
+}
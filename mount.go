@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Modes of a mount. A ReadOnly mount rejects every change to its content, while a CopyOnWrite mount takes a copy of
+// the content of its target on the first change and becomes an ordinary directory.
+const (
+	ReadOnlyMount    = "ReadOnly"
+	CopyOnWriteMount = "CopyOnWrite"
+)
+
+var readOnlyMountError = fmt.Errorf("can't modify a read-only mount")
+
+func ValidateMountMode(mode string) error {
+	switch mode {
+	case ReadOnlyMount, CopyOnWriteMount:
+		return nil
+	}
+	return fmt.Errorf("invalid mount mode %q, expect %s or %s", mode, ReadOnlyMount, CopyOnWriteMount)
+}
+
+//IsMount Check whether the directory shows the content of another directory instead of its own.
+func (d *Directory) IsMount() bool {
+	return d.Target != ""
+}
+
+//Materialize Take a copy of the files and children of target and turn the mount into an ordinary directory. The
+//children themselves are shared with target, only the listing is copied.
+func (d *Directory) Materialize(target *Directory) {
+	d.Files = make([]*FileMeta, 0)
+	for _, file := range target.Files {
+		copied := *file
+		d.Files = append(d.Files, &copied)
+	}
+	d.Directories = append([]string{}, target.Directories...)
+	d.Usage = filesSize(d.Files)
+	d.Target = ""
+	d.MountMode = ""
+}
+
+// getMountTarget reads the target of a mount. The caller must be able to read the target.
+func getMountTarget(ctx contractapi.TransactionContextInterface, mount *Directory) (*Directory, error) {
+	target, err := getDirectory(ctx, mount.Target)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := target.CheckPrivilege(ctx, Subscriber)
+	if err != nil {
+		return nil, err
+	}
+	if !ok && !target.IsReadableByKey() {
+		return nil, privilegeError
+	}
+	return target, nil
+}
+
+// resolveMount returns the directory itself, or a view of a mount holding the content of its target.
+func resolveMount(ctx contractapi.TransactionContextInterface, directory *Directory) (*Directory, error) {
+	if !directory.IsMount() {
+		return directory, nil
+	}
+	target, err := getMountTarget(ctx, directory)
+	if err != nil {
+		return nil, err
+	}
+	view := *directory
+	view.Files = target.Files
+	view.Directories = target.Directories
+	return &view, nil
+}
+
+// prepareWrite must be called before changing the files or children of a directory. It rejects changes to read-only
+// mounts and materializes copy-on-write mounts, indexing the tags of the files they take over.
+func prepareWrite(ctx contractapi.TransactionContextInterface, key string, directory *Directory) error {
+	if !directory.IsMount() {
+		return nil
+	}
+	if directory.MountMode == ReadOnlyMount {
+		return readOnlyMountError
+	}
+	target, err := getMountTarget(ctx, directory)
+	if err != nil {
+		return err
+	}
+	directory.Materialize(target)
+	return indexAllTags(ctx, key, directory)
+}
@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestReadOnlyMount_RejectsChanges(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	target := l.directory(alice, "target", Private)
+	parent := l.directory(alice, "parent", Private)
+	_, err := l.contract.AddFile(l.as(alice), target, []*FileMeta{{Name: "q1.pdf"}})
+	l.must(err)
+	mount, err := l.contract.CreateMount(l.as(alice), parent, target, "mount", ReadOnlyMount)
+	l.must(err)
+
+	if _, err = l.contract.AddFile(l.as(alice), mount, []*FileMeta{{Name: "q2.pdf"}}); err != readOnlyMountError {
+		t.Errorf("adding a file to a read-only mount should fail, got %v", err)
+	}
+	if _, err = l.contract.RemoveFile(l.as(alice), mount, []string{"q1.pdf"}); err != readOnlyMountError {
+		t.Errorf("removing a file from a read-only mount should fail, got %v", err)
+	}
+	if !l.read(mount).IsMount() || len(l.read(target).Files) != 1 {
+		t.Errorf("mount and target should be left unchanged")
+	}
+}
+
+func TestCopyOnWriteMount_Materializes(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	target := l.directory(alice, "target", Private)
+	parent := l.directory(alice, "parent", Private)
+	_, err := l.contract.AddFile(l.as(alice), target, []*FileMeta{{Name: "q1.pdf"}})
+	l.must(err)
+	mount, err := l.contract.CreateMount(l.as(alice), parent, target, "mount", CopyOnWriteMount)
+	l.must(err)
+
+	_, err = l.contract.AddFile(l.as(alice), mount, []*FileMeta{{Name: "q2.pdf"}})
+	l.must(err)
+	if directory := l.read(mount); directory.IsMount() || len(directory.Files) != 2 {
+		t.Errorf("mount should become a directory holding both files, got %s", directory.ToString())
+	}
+	if files := l.read(target).Files; len(files) != 1 {
+		t.Errorf("target should be left unchanged, got %d files", len(files))
+	}
+}
+
+func TestReadDirectory_MountRequiresTargetAccess(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	target := l.directory(alice, "target", Private)
+	parent := l.directory(alice, "parent", Private)
+	_, err := l.contract.AddFile(l.as(alice), target, []*FileMeta{{Name: "q1.pdf"}})
+	l.must(err)
+	l.must(l.contract.AddSubscribers(l.as(alice), parent, []string{bob.ID()}, false))
+	mount, err := l.contract.CreateMount(l.as(alice), parent, target, "mount", ReadOnlyMount)
+	l.must(err)
+
+	directory, err := l.contract.ReadDirectory(l.as(alice), mount)
+	l.must(err)
+	if len(directory.Files) != 1 || directory.Files[0].Name != "q1.pdf" {
+		t.Errorf("mount should show the content of its target")
+	}
+	if _, err = l.contract.ReadDirectory(l.as(bob), mount); err != privilegeError {
+		t.Errorf("mount should not show a target the caller can't read, got %v", err)
+	}
+	directories, err := l.contract.ReadDirectories(l.as(bob), []string{mount})
+	l.must(err)
+	if len(directories) != 0 {
+		t.Errorf("mount should not be listed to a caller who can't read its target")
+	}
+
+	l.must(l.contract.AddSubscribers(l.as(alice), target, []string{bob.ID()}, false))
+	directory, err = l.contract.ReadDirectory(l.as(bob), mount)
+	l.must(err)
+	if len(directory.Files) != 1 || directory.Files[0].Name != "q1.pdf" {
+		t.Errorf("mount should show the target to a caller who can read it")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "fmt"
+
+// OrganizationGrant gives every identity of an MSP a role on a directory. If Attributes is not empty, only identities
+// whose certificate carries all of the attributes with the same values are granted.
+type OrganizationGrant struct {
+	MSPID      string            `json:"mspId"`
+	Role       string            `json:"role"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// AttributeLookup returns the value of a certificate attribute and whether the certificate has it.
+type AttributeLookup = func(name string) (string, bool, error)
+
+func NewOrganizationGrant(mspID, role string, attributes map[string]string) (*OrganizationGrant, error) {
+	if mspID == "" {
+		return nil, fmt.Errorf("msp id can't be empty")
+	}
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	if attributes == nil {
+		attributes = make(map[string]string)
+	}
+	return &OrganizationGrant{MSPID: mspID, Role: role, Attributes: attributes}, nil
+}
+
+func (g *OrganizationGrant) Grants(privilege Privilege) bool {
+	return RoleGrants(g.Role, privilege)
+}
+
+func (g *OrganizationGrant) Matches(mspID string, lookup AttributeLookup) (bool, error) {
+	if g.MSPID != mspID {
+		return false, nil
+	}
+	for name, expected := range g.Attributes {
+		value, found, err := lookup(name)
+		if err != nil {
+			return false, err
+		}
+		if !found || value != expected {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestOrganizationGrant_Matches(t *testing.T) {
+	attributes := map[string]string{"department": "finance"}
+	lookup := func(name string) (string, bool, error) {
+		value, found := attributes[name]
+		return value, found, nil
+	}
+
+	grant, err := NewOrganizationGrant("Org2MSP", SubscriberRole, map[string]string{"department": "finance"})
+	if err != nil {
+		t.Fatalf("fail to create grant: %v", err)
+	}
+	if ok, _ := grant.Matches("Org2MSP", lookup); !ok {
+		t.Errorf("should match msp and attributes")
+	}
+	if ok, _ := grant.Matches("Org1MSP", lookup); ok {
+		t.Errorf("should not match other msp")
+	}
+	attributes["department"] = "sales"
+	if ok, _ := grant.Matches("Org2MSP", lookup); ok {
+		t.Errorf("should not match other attribute value")
+	}
+	if grant.Grants(Cooperator) || !grant.Grants(Subscriber) {
+		t.Errorf("subscriber grant should only allow reading")
+	}
+	if _, err = NewOrganizationGrant("Org2MSP", "Owner", nil); err == nil {
+		t.Errorf("should reject unknown role")
+	}
+}
+
+func TestGrantOrganization_CantHandOutAccess(t *testing.T) {
+	l := newTestLedger(t)
+	alice, dave := l.user("alice"), l.user("dave")
+	key := l.directory(alice, "reports", Private)
+	l.must(l.contract.GrantOrganization(l.as(alice), key, "Org3MSP", CooperatorRole, nil, false))
+	l.must(l.contract.SetAccessPolicy(l.as(alice), key, CooperatorRole, "Org4MSP", "department = finance", false))
+
+	member := newTestIdentity("member")
+	member.mspID = "Org3MSP"
+	analyst := newTestIdentity("analyst")
+	analyst.mspID = "Org4MSP"
+	analyst.attributes["department"] = "finance"
+	for _, identity := range []*testIdentity{member, analyst} {
+		l.user(identity.name)
+		_, err := l.contract.AddFile(l.as(identity), key, []*FileMeta{{Name: identity.name + ".txt"}})
+		l.must(err)
+		if err = l.contract.AddCooperators(l.as(identity), key, []string{identity.ID(), dave.ID()}, false); err != privilegeError {
+			t.Errorf("%s should not add cooperators through an organization right, got %v", identity.name, err)
+		}
+		if err = l.contract.AddSubscribers(l.as(identity), key, []string{dave.ID()}, false); err != privilegeError {
+			t.Errorf("%s should not add subscribers through an organization right, got %v", identity.name, err)
+		}
+		if _, err = l.contract.ShareFile(l.as(identity), key, identity.name+".txt", []string{dave.ID()}); err != privilegeError {
+			t.Errorf("%s should not share files through an organization right, got %v", identity.name, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const ownershipTransferIndex = "ownershipTransfer"
+
+// OwnershipTransfer is a transfer of ownership waiting for the acceptance of the new owner. It is also the payload of
+// the OwnershipTransferred event.
+type OwnershipTransfer struct {
+	Key       string `json:"key"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Recursive bool   `json:"recursive"`
+	Date      int64  `json:"date"`
+}
+
+func getOwnershipTransfer(ctx contractapi.TransactionContextInterface, key string) (string, *OwnershipTransfer, error) {
+	transferKey, err := ctx.GetStub().CreateCompositeKey(ownershipTransferIndex, []string{key})
+	if err != nil {
+		return "", nil, err
+	}
+	transfer := new(OwnershipTransfer)
+	if err = GetJsonState(ctx, transferKey, transfer); err != nil {
+		return transferKey, nil, fmt.Errorf("ownership transfer doesn't exist")
+	}
+	return transferKey, transfer, nil
+}
+
+// transferIteration hands every directory of the tree owned by from over to to. The previous owner stays a cooperator.
+func transferIteration(ctx contractapi.TransactionContextInterface, dirKey string, from, to, toName string, recursive bool, root bool) error {
+	dir, err := getDirectory(ctx, dirKey)
+	if err != nil {
+		return err
+	}
+
+	if dir.IsCreator(from) {
+		dir.Creator = to
+		dir.AddCooperators([]string{to}, []string{toName})
+		delete(dir.CooperatorTerms, to)
+		if err = dir.Save(ctx, dirKey); err != nil {
+			return err
+		}
+		if err = removeOwnerIndex(ctx, dirKey, from); err != nil {
+			return err
+		}
+		if err = updateAccessIndex(ctx, dirKey, dir, []string{from, to}, root); err != nil {
+			return err
+		}
+	} else if root {
+		return privilegeError
+	}
+
+	if recursive {
+		for _, dirKey := range dir.Directories {
+			if err = transferIteration(ctx, dirKey, from, to, toName, recursive, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func transferOwnership(ctx contractapi.TransactionContextInterface, transfer *OwnershipTransfer) error {
+	ctx = readYourWrites(ctx)
+	toName, err := getNameByID(ctx, []string{transfer.To})
+	if err != nil {
+		return err
+	}
+	if err = transferIteration(ctx, transfer.Key, transfer.From, transfer.To, toName[0], transfer.Recursive, true); err != nil {
+		return err
+	}
+	return SetJsonEvent(ctx, "OwnershipTransferred", transfer)
+}
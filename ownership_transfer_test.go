@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestTransferOwnership_RequiresAcceptance(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol := l.user("alice"), l.user("bob"), l.user("carol")
+	key := l.directory(alice, "reports", Private)
+
+	if err := l.contract.TransferOwnership(l.as(bob), key, carol.ID(), false, true); err != privilegeError {
+		t.Errorf("only the creator should transfer ownership, got %v", err)
+	}
+	if err := l.contract.TransferOwnership(l.as(alice), key, newTestIdentity("nobody").ID(), false, true); err == nil {
+		t.Errorf("transfer to a user without profile should fail")
+	}
+
+	l.must(l.contract.TransferOwnership(l.as(alice), key, bob.ID(), false, true))
+	if !l.read(key).IsCreator(alice.ID()) {
+		t.Errorf("ownership should not change before the transfer is accepted")
+	}
+	for _, identity := range []*testIdentity{alice, carol} {
+		if err := l.contract.AcceptOwnership(l.as(identity), key); err != privilegeError {
+			t.Errorf("only the new owner should accept the transfer, got %v", err)
+		}
+	}
+
+	l.must(l.contract.AcceptOwnership(l.as(bob), key))
+	if !l.read(key).IsCreator(bob.ID()) {
+		t.Errorf("accepted transfer should make the new owner the creator")
+	}
+	if err := l.contract.AcceptOwnership(l.as(bob), key); err == nil {
+		t.Errorf("accepted transfer should not be accepted again")
+	}
+}
+
+func TestDeclineOwnership(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol := l.user("alice"), l.user("bob"), l.user("carol")
+	key := l.directory(alice, "reports", Private)
+
+	l.must(l.contract.TransferOwnership(l.as(alice), key, bob.ID(), false, true))
+	if err := l.contract.DeclineOwnership(l.as(carol), key); err != privilegeError {
+		t.Errorf("only the parties of the transfer should decline it, got %v", err)
+	}
+	l.must(l.contract.DeclineOwnership(l.as(alice), key))
+	if err := l.contract.AcceptOwnership(l.as(bob), key); err == nil {
+		t.Errorf("declined transfer should not be accepted")
+	}
+	if !l.read(key).IsCreator(alice.ID()) {
+		t.Errorf("declined transfer should keep the owner")
+	}
+}
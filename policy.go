@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AccessPolicy grants a role to every identity of the organization MSPID whose certificate attributes satisfy
+// Expression. Identities of other organizations never match, since any organization's CA can issue any attribute.
+//
+// Expressions compare attributes with values and combine comparisons with AND, OR, NOT and parentheses, e.g.
+// `department = finance AND clearance >= 2`. Supported operators are =, !=, >, >=, < and <=. Values are numbers, bare
+// words or double quoted strings. Ordering operators compare numerically and need a numeric value. A comparison on an
+// attribute the identity doesn't have is unknown rather than false. Unknown stays unknown under NOT, and a policy only
+// matches if it evaluates to true, so `NOT clearance = 0` doesn't match identities without a clearance.
+type AccessPolicy struct {
+	Role       string `json:"role"`
+	MSPID      string `json:"mspId"`
+	Expression string `json:"expression"`
+}
+
+type Policy struct {
+	root policyNode
+}
+
+// policyTruth is the three-valued result of evaluating a policy.
+type policyTruth int
+
+const (
+	policyFalse policyTruth = iota
+	policyTrue
+	policyUnknown
+)
+
+type policyNode interface {
+	evaluate(lookup AttributeLookup) (policyTruth, error)
+}
+
+type andNode struct {
+	left, right policyNode
+}
+
+type orNode struct {
+	left, right policyNode
+}
+
+type notNode struct {
+	operand policyNode
+}
+
+type comparisonNode struct {
+	attribute string
+	operator  string
+	value     string
+}
+
+func (n *andNode) evaluate(lookup AttributeLookup) (policyTruth, error) {
+	left, err := n.left.evaluate(lookup)
+	if err != nil || left == policyFalse {
+		return policyFalse, err
+	}
+	right, err := n.right.evaluate(lookup)
+	if err != nil || right == policyFalse {
+		return policyFalse, err
+	}
+	if left == policyTrue && right == policyTrue {
+		return policyTrue, nil
+	}
+	return policyUnknown, nil
+}
+
+func (n *orNode) evaluate(lookup AttributeLookup) (policyTruth, error) {
+	left, err := n.left.evaluate(lookup)
+	if err != nil || left == policyTrue {
+		return left, err
+	}
+	right, err := n.right.evaluate(lookup)
+	if err != nil || right == policyTrue {
+		return right, err
+	}
+	if left == policyFalse && right == policyFalse {
+		return policyFalse, nil
+	}
+	return policyUnknown, nil
+}
+
+func (n *notNode) evaluate(lookup AttributeLookup) (policyTruth, error) {
+	operand, err := n.operand.evaluate(lookup)
+	switch operand {
+	case policyTrue:
+		return policyFalse, err
+	case policyFalse:
+		return policyTrue, err
+	}
+	return policyUnknown, err
+}
+
+func truthOf(ok bool) policyTruth {
+	if ok {
+		return policyTrue
+	}
+	return policyFalse
+}
+
+func (n *comparisonNode) evaluate(lookup AttributeLookup) (policyTruth, error) {
+	value, found, err := lookup(n.attribute)
+	if err != nil {
+		return policyFalse, err
+	}
+	if !found {
+		return policyUnknown, nil
+	}
+
+	switch n.operator {
+	case "=":
+		return truthOf(compareEqual(value, n.value)), nil
+	case "!=":
+		return truthOf(!compareEqual(value, n.value)), nil
+	}
+
+	actual, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return policyUnknown, nil
+	}
+	expected, _ := strconv.ParseFloat(n.value, 64)
+	switch n.operator {
+	case ">":
+		return truthOf(actual > expected), nil
+	case ">=":
+		return truthOf(actual >= expected), nil
+	case "<":
+		return truthOf(actual < expected), nil
+	default:
+		return truthOf(actual <= expected), nil
+	}
+}
+
+func compareEqual(actual, expected string) bool {
+	actualNumber, err := strconv.ParseFloat(actual, 64)
+	if err != nil {
+		return actual == expected
+	}
+	expectedNumber, err := strconv.ParseFloat(expected, 64)
+	if err != nil {
+		return actual == expected
+	}
+	return actualNumber == expectedNumber
+}
+
+//Evaluate Check whether the attributes satisfy the policy. Unknown results don't match.
+func (p *Policy) Evaluate(lookup AttributeLookup) (bool, error) {
+	truth, err := p.root.evaluate(lookup)
+	return truth == policyTrue, err
+}
+
+// ParsePolicy parses and validates a policy expression.
+func ParsePolicy(expression string) (*Policy, error) {
+	tokens, err := tokenizePolicy(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("policy expression is empty")
+	}
+
+	parser := &policyParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.position < len(tokens) {
+		return nil, fmt.Errorf("unexpected %q in policy expression", tokens[parser.position].text)
+	}
+	return &Policy{root: root}, nil
+}
+
+const (
+	wordToken = iota
+	stringToken
+	operatorToken
+	parenthesisToken
+)
+
+type policyToken struct {
+	kind int
+	text string
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-:@", r)
+}
+
+func tokenizePolicy(expression string) ([]policyToken, error) {
+	runes := []rune(expression)
+	tokens := make([]policyToken, 0)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, policyToken{parenthesisToken, string(r)})
+			i++
+		case r == '=':
+			tokens = append(tokens, policyToken{operatorToken, "="})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, policyToken{operatorToken, string(runes[i : i+2])})
+				i += 2
+			} else if r == '!' {
+				return nil, fmt.Errorf("unexpected '!' in policy expression")
+			} else {
+				tokens = append(tokens, policyToken{operatorToken, string(r)})
+				i++
+			}
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string in policy expression")
+			}
+			tokens = append(tokens, policyToken{stringToken, string(runes[i+1 : end])})
+			i = end + 1
+		case isWordRune(r):
+			end := i
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, policyToken{wordToken, string(runes[i:end])})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected %q in policy expression", r)
+		}
+	}
+	return tokens, nil
+}
+
+type policyParser struct {
+	tokens   []policyToken
+	position int
+}
+
+func (p *policyParser) peek() *policyToken {
+	if p.position >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.position]
+}
+
+func (p *policyParser) acceptKeyword(keyword string) bool {
+	token := p.peek()
+	if token != nil && token.kind == wordToken && strings.EqualFold(token.text, keyword) {
+		p.position++
+		return true
+	}
+	return false
+}
+
+func (p *policyParser) parseOr() (policyNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *policyParser) parseAnd() (policyNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *policyParser) parseUnary() (policyNode, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+	}
+
+	token := p.peek()
+	if token == nil {
+		return nil, fmt.Errorf("unexpected end of policy expression")
+	}
+	if token.kind == parenthesisToken && token.text == "(" {
+		p.position++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		token = p.peek()
+		if token == nil || token.kind != parenthesisToken || token.text != ")" {
+			return nil, fmt.Errorf("missing ')' in policy expression")
+		}
+		p.position++
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *policyParser) parseComparison() (policyNode, error) {
+	attribute := p.peek()
+	if attribute == nil || attribute.kind != wordToken {
+		return nil, fmt.Errorf("expect an attribute name in policy expression")
+	}
+	p.position++
+
+	operator := p.peek()
+	if operator == nil || operator.kind != operatorToken {
+		return nil, fmt.Errorf("expect an operator after %q in policy expression", attribute.text)
+	}
+	p.position++
+
+	value := p.peek()
+	if value == nil || (value.kind != wordToken && value.kind != stringToken) {
+		return nil, fmt.Errorf("expect a value after %q in policy expression", operator.text)
+	}
+	p.position++
+
+	if operator.text != "=" && operator.text != "!=" {
+		if _, err := strconv.ParseFloat(value.text, 64); err != nil {
+			return nil, fmt.Errorf("operator %s needs a numeric value", operator.text)
+		}
+	}
+	return &comparisonNode{attribute.text, operator.text, value.text}, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import "testing"
+
+func TestParsePolicy(t *testing.T) {
+	valid := []string{
+		"department = finance",
+		`department = "human resources" AND clearance >= 2`,
+		"NOT (role = intern OR clearance < 1)",
+		"a=1 and b!=2 or c>3",
+	}
+	for _, expression := range valid {
+		if _, err := ParsePolicy(expression); err != nil {
+			t.Errorf("should parse %q: %v", expression, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"department",
+		"department =",
+		"clearance >= high",
+		"(department = finance",
+		"department = finance AND",
+		"department = finance clearance = 2",
+		`department = "finance`,
+		"department ! finance",
+	}
+	for _, expression := range invalid {
+		if _, err := ParsePolicy(expression); err == nil {
+			t.Errorf("should reject %q", expression)
+		}
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	attributes := map[string]string{"department": "finance", "clearance": "2", "role": "analyst"}
+	lookup := func(name string) (string, bool, error) {
+		value, found := attributes[name]
+		return value, found, nil
+	}
+
+	cases := map[string]bool{
+		"department = finance AND clearance >= 2":                   true,
+		"department = finance AND clearance > 2":                    false,
+		"department = sales OR clearance = 2.0":                     true,
+		"NOT department = finance":                                  false,
+		"NOT (role = intern OR clearance < 1)":                      true,
+		"team = blue":                                               false,
+		"team != blue":                                              false,
+		"NOT team = blue":                                           false,
+		"NOT (team = blue AND department = sales)":                  true,
+		"NOT (team = blue OR department = sales)":                   false,
+		"team = blue OR department = finance":                       true,
+		"department = sales OR role = analyst AND clearance <= 1":   false,
+		"(department = sales OR role = analyst) AND clearance <= 2": true,
+	}
+	for expression, expected := range cases {
+		policy, err := ParsePolicy(expression)
+		if err != nil {
+			t.Errorf("should parse %q: %v", expression, err)
+			continue
+		}
+		ok, err := policy.Evaluate(lookup)
+		if err != nil || ok != expected {
+			t.Errorf("%q should evaluate to %v", expression, expected)
+		}
+	}
+}
+
+func TestSetAccessPolicy_BindsOrganization(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	key := l.directory(alice, "reports", Private)
+	l.must(l.contract.SetAccessPolicy(l.as(alice), key, SubscriberRole, "Org1MSP", "department = finance", false))
+
+	if err := l.contract.SetAccessPolicy(l.as(alice), key, SubscriberRole, "", "department = finance", false); err == nil {
+		t.Errorf("access policy without msp id should be rejected")
+	}
+
+	for mspID, expected := range map[string]bool{"Org1MSP": true, "Org2MSP": false} {
+		bob := newTestIdentity("bob")
+		bob.mspID = mspID
+		bob.attributes["department"] = "finance"
+		ok, err := l.read(key).CheckPrivilege(l.as(bob), Subscriber)
+		l.must(err)
+		if ok != expected {
+			t.Errorf("policy of Org1MSP should match identity of %s: %v", mspID, expected)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+var quotaError = fmt.Errorf("storage quota exceeded")
+
+// Usage is the storage used by a user or a directory in bytes. A quota of zero means no limit. The usage of a user
+// covers every directory it owns, the usage of a directory only its own files.
+type Usage struct {
+	Target string `json:"target"`
+	Usage  int64  `json:"usage"`
+	Quota  int64  `json:"quota"`
+}
+
+func filesSize(files []*FileMeta) int64 {
+	var size int64
+	for _, file := range files {
+		size += file.Size
+	}
+	return size
+}
+
+func validateQuota(quota int64) error {
+	if quota < 0 {
+		return fmt.Errorf("quota can't be negative")
+	}
+	return nil
+}
+
+//CheckQuota Check whether the files of the directory fit into its quota.
+func (d *Directory) CheckQuota() error {
+	if d.Quota > 0 && d.Usage > d.Quota {
+		return quotaError
+	}
+	return nil
+}
+
+//ChargeUsage Add delta bytes to the usage of the user. Growing beyond the quota fails, shrinking always succeeds.
+func (u *UserProfile) ChargeUsage(delta int64) error {
+	u.Usage += delta
+	if u.Usage < 0 {
+		u.Usage = 0
+	}
+	if delta > 0 && u.Quota > 0 && u.Usage > u.Quota {
+		return quotaError
+	}
+	return nil
+}
+
+// chargeUsage adds delta bytes to the usage of a user.
+func chargeUsage(ctx contractapi.TransactionContextInterface, id string, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	profile, err := getUserProfile(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = profile.ChargeUsage(delta); err != nil {
+		return err
+	}
+	return PutJsonState(ctx, id, profile)
+}
+
+// chargeDirectoryUsage charges the owner of a directory for the change of its usage. A new owner takes over the whole
+// usage from the previous one.
+func chargeDirectoryUsage(ctx contractapi.TransactionContextInterface, previous *Directory, current *Directory) error {
+	if previous.Creator == current.Creator {
+		return chargeUsage(ctx, current.Creator, current.Usage-previous.Usage)
+	}
+	if previous.Creator != "" {
+		if err := chargeUsage(ctx, previous.Creator, -previous.Usage); err != nil {
+			return err
+		}
+	}
+	return chargeUsage(ctx, current.Creator, current.Usage)
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestCopyDirectory_KeepsQuota(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	source := l.directory(alice, "source", Private)
+	destination := l.directory(alice, "destination", Private)
+	_, err := l.contract.AddFile(l.as(alice), source, []*FileMeta{{Name: "a.txt", Size: 100}})
+	l.must(err)
+	_, err = l.contract.SetDirectoryQuota(l.as(alice), source, 150)
+	l.must(err)
+
+	keys, err := l.contract.CopyDirectory(l.as(alice), source, destination)
+	l.must(err)
+	if l.read(keys[source]).Quota != 150 {
+		t.Errorf("copy should keep the quota of its source")
+	}
+
+	_, err = l.contract.SetDirectoryQuota(l.as(alice), source, 50)
+	l.must(err)
+	if _, err = l.contract.CopyDirectory(l.as(alice), source, destination); err != quotaError {
+		t.Errorf("copy exceeding the quota of its source should fail, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"strconv"
+	"strings"
+)
+
+// nameIndex maps every suffix of the lower case name of a file or directory to the item, with one key attribute per
+// character. A partial composite key of the characters of a query then finds the names containing it, and the entries
+// of the suffixes starting at the first character the names starting with it.
+const nameIndex = "name"
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+	// maxIndexedNameLength bounds the index entries of a name: only suffixes starting within the first
+	// maxIndexedNameLength characters are indexed, each cut off after maxIndexedNameLength characters.
+	maxIndexedNameLength = 64
+	// maxSearchAncestors bounds the directories visited while deciding whether a match lies below the search root.
+	maxSearchAncestors = 1000
+)
+
+// NamedItem is an entry of the name index. It refers to a directory, or to a file of it if FileName is set, whose name
+// contains the indexed suffix from character Start on.
+type NamedItem struct {
+	Key      string `json:"key"`
+	FileName string `json:"fileName,omitempty"`
+	Start    int    `json:"start"`
+}
+
+// SearchResult is a directory or, if FileName is set, a file of it whose name matches a search.
+type SearchResult struct {
+	Key       string     `json:"key"`
+	FileName  string     `json:"fileName,omitempty"`
+	Directory *Directory `json:"directory,omitempty"`
+	File      *FileMeta  `json:"file,omitempty"`
+}
+
+// SearchResults are the results of a search. Truncated is set if more matches exist beyond the result limit.
+type SearchResults struct {
+	Results   []*SearchResult `json:"results"`
+	Truncated bool            `json:"truncated"`
+}
+
+// matchName reports whether name contains query, or starts with it if prefixOnly is set, ignoring case. The query
+// must already be in lower case.
+func matchName(name, query string, prefixOnly bool) bool {
+	name = strings.ToLower(name)
+	if prefixOnly {
+		return strings.HasPrefix(name, query)
+	}
+	return strings.Contains(name, query)
+}
+
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		return maxSearchLimit
+	}
+	return limit
+}
+
+// nameAttributes returns the lower case characters of name as key attributes of the name index.
+func nameAttributes(name string) []string {
+	attributes := make([]string, 0)
+	for _, r := range strings.ToLower(name) {
+		if len(attributes) == maxIndexedNameLength {
+			break
+		}
+		attributes = append(attributes, string(r))
+	}
+	return attributes
+}
+
+// namesOf returns the names of directory and its files by file name, the directory itself under the empty name.
+func namesOf(directory *Directory) map[string]string {
+	names := make(map[string]string)
+	if directory.Name != "" {
+		names[""] = directory.Name
+	}
+	for _, file := range directory.Files {
+		names[file.Name] = file.Name
+	}
+	return names
+}
+
+// updateNameIndex synchronizes the name index with the names of a directory and its files, given its previous version.
+func updateNameIndex(ctx contractapi.TransactionContextInterface, key string, previous *Directory, current *Directory) error {
+	before, after := namesOf(previous), namesOf(current)
+	for fileName, name := range before {
+		if after[fileName] == name {
+			continue
+		}
+		if err := putNameEntries(ctx, key, fileName, name, false); err != nil {
+			return err
+		}
+	}
+	for fileName, name := range after {
+		if before[fileName] == name {
+			continue
+		}
+		if err := putNameEntries(ctx, key, fileName, name, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putNameEntries(ctx contractapi.TransactionContextInterface, key, fileName, name string, present bool) error {
+	characters := make([]string, 0)
+	for _, r := range strings.ToLower(name) {
+		characters = append(characters, string(r))
+	}
+	for start := 0; start < len(characters) && start < maxIndexedNameLength; start++ {
+		suffix := characters[start:]
+		if len(suffix) > maxIndexedNameLength {
+			suffix = suffix[:maxIndexedNameLength]
+		}
+		// The start attribute has more than one character, so it never matches a character of a query.
+		attributes := append(append([]string{}, suffix...), "@"+strconv.Itoa(start), key, fileName)
+		indexKey, err := ctx.GetStub().CreateCompositeKey(nameIndex, attributes)
+		if err != nil {
+			return err
+		}
+		if !present {
+			err = ctx.GetStub().DelState(indexKey)
+		} else {
+			err = PutJsonState(ctx, indexKey, &NamedItem{Key: key, FileName: fileName, Start: start})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listNamedItems calls found with the entries of the name index whose name contains query, or starts with it if
+// prefixOnly is set, until found returns false. Queries longer than maxIndexedNameLength are looked up by their
+// beginning, so found must check the full name.
+func listNamedItems(ctx contractapi.TransactionContextInterface, query string, prefixOnly bool, found func(item *NamedItem) (bool, error)) error {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(nameIndex, nameAttributes(query))
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		item := new(NamedItem)
+		if err = json.Unmarshal(kv.GetValue(), item); err != nil {
+			return err
+		}
+		if prefixOnly && item.Start != 0 {
+			continue
+		}
+		if more, err := found(item); err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBelow reports whether key is root or one of its descendants, walking the parent index upwards. Directories whose
+// parents aren't indexed yet, and trees deeper than maxSearchAncestors, aren't found.
+func isBelow(ctx contractapi.TransactionContextInterface, key string, root string) (bool, error) {
+	visited := map[string]bool{key: true}
+	queue := []string{key}
+	for i := 0; i < len(queue) && i < maxSearchAncestors; i++ {
+		if queue[i] == root {
+			return true, nil
+		}
+		parents, err := listParents(ctx, queue[i])
+		if err != nil {
+			return false, err
+		}
+		for _, parent := range parents {
+			if !visited[parent] {
+				visited[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return false, nil
+}
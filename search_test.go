@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchName(t *testing.T) {
+	if !matchName("Annual Report.pdf", "report", false) {
+		t.Errorf("substring should match ignoring case")
+	}
+	if matchName("Annual Report.pdf", "report", true) {
+		t.Errorf("prefix search should not match a substring")
+	}
+	if !matchName("Report.pdf", "rep", true) {
+		t.Errorf("prefix should match ignoring case")
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	if searchLimit(0) != defaultSearchLimit || searchLimit(5) != 5 || searchLimit(maxSearchLimit+1) != maxSearchLimit {
+		t.Errorf("unexpected search limit")
+	}
+}
+
+func TestSearchByName_Truncated(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	key := l.directory(alice, "reports", Private)
+	_, err := l.contract.AddFile(l.as(alice), key, []*FileMeta{{Name: "q1.pdf"}, {Name: "q2.pdf"}, {Name: "q3.pdf"}})
+	l.must(err)
+
+	results, err := l.contract.SearchByName(l.as(alice), "q", key, 2, true)
+	l.must(err)
+	if len(results.Results) != 2 || !results.Truncated {
+		t.Errorf("search stopped by its limit should be truncated, got %d results", len(results.Results))
+	}
+	results, err = l.contract.SearchByName(l.as(alice), "q", key, 3, true)
+	l.must(err)
+	if len(results.Results) != 3 || results.Truncated {
+		t.Errorf("complete search should not be truncated, got %d results", len(results.Results))
+	}
+}
+
+func searchKeys(results *SearchResults) map[string]bool {
+	keys := make(map[string]bool)
+	for _, result := range results.Results {
+		keys[result.Key+"/"+result.FileName] = true
+	}
+	return keys
+}
+
+func TestSearchByName_NameIndex(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	profile, err := getUserProfile(l.as(alice), alice.ID())
+	l.must(err)
+	reports, err := l.contract.CreateDirectory(l.as(alice), "Reports", Private, profile.Private, false)
+	l.must(err)
+	_, err = l.contract.AddFile(l.as(alice), reports, []*FileMeta{{Name: "Annual Report.pdf"}, {Name: "notes.txt"}})
+	l.must(err)
+	outside := l.directory(alice, "report drafts", Private)
+	foreign := l.directory(bob, "report of bob", Private)
+
+	results, err := l.contract.SearchByName(l.as(alice), "REPORT", "", 0, false)
+	l.must(err)
+	keys := searchKeys(results)
+	if len(keys) != 2 || !keys[reports+"/"] || !keys[reports+"/Annual Report.pdf"] || results.Truncated {
+		t.Errorf("substring search should find the directory and the file below the root, got %v", keys)
+	}
+	if keys[outside+"/"] || keys[foreign+"/"] {
+		t.Errorf("search should leave out directories outside the root")
+	}
+
+	results, err = l.contract.SearchByName(l.as(alice), "report", "", 0, true)
+	l.must(err)
+	if keys = searchKeys(results); len(keys) != 1 || !keys[reports+"/"] {
+		t.Errorf("prefix search should only find names starting with the query, got %v", keys)
+	}
+
+	results, err = l.contract.SearchByName(l.as(bob), "report", reports, 0, false)
+	l.must(err)
+	if len(results.Results) != 0 {
+		t.Errorf("search should leave out directories the caller can't read")
+	}
+
+	_, err = l.contract.RemoveFile(l.as(alice), reports, []string{"Annual Report.pdf"})
+	l.must(err)
+	_, err = l.contract.RenameDirectory(l.as(alice), reports, "Archive")
+	l.must(err)
+	results, err = l.contract.SearchByName(l.as(alice), "report", "", 0, false)
+	l.must(err)
+	if len(results.Results) != 0 {
+		t.Errorf("removed and renamed names should leave the index, got %v", searchKeys(results))
+	}
+	results, err = l.contract.SearchByName(l.as(alice), "chiv", "", 0, false)
+	l.must(err)
+	if keys = searchKeys(results); len(keys) != 1 || !keys[reports+"/"] {
+		t.Errorf("new name should be indexed, got %v", keys)
+	}
+}
+
+func TestSearchByName_LongNames(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	key := l.directory(alice, "long", Private)
+	name := strings.Repeat("a", maxIndexedNameLength) + "-tail.txt"
+	_, err := l.contract.AddFile(l.as(alice), key, []*FileMeta{{Name: name}})
+	l.must(err)
+
+	results, err := l.contract.SearchByName(l.as(alice), name, key, 0, true)
+	l.must(err)
+	if len(results.Results) != 1 {
+		t.Errorf("query longer than the indexed part should match the full name")
+	}
+	results, err = l.contract.SearchByName(l.as(alice), strings.Repeat("a", maxIndexedNameLength)+"-other", key, 0, true)
+	l.must(err)
+	if len(results.Results) != 0 {
+		t.Errorf("query should be checked against the full name")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	shareLinkIndex          = "shareLink"
+	directoryShareLinkIndex = "directoryShareLink"
+)
+
+// ShareLinkSecretField is the transient field carrying the secret of a share link, so that the secret itself never
+// ends up in a block.
+const ShareLinkSecretField = "secret"
+
+const minShareLinkSecretLength = 16
+
+// ShareLink grants its role on a directory to whoever redeems its secret. Only the hash of the secret is stored, and
+// it doubles as the id of the link.
+type ShareLink struct {
+	Id      string `json:"id"`
+	Key     string `json:"key"`
+	Role    string `json:"role"`
+	Creator string `json:"creator"`
+	Date    int64  `json:"date"`
+	DueDate int64  `json:"dueDate"`
+	MaxUses int64  `json:"maxUses"`
+	Uses    int64  `json:"uses"`
+}
+
+//CheckRedeemable Check whether the link can be redeemed once more at timestamp.
+func (l *ShareLink) CheckRedeemable(timestamp int64) error {
+	if l.DueDate > 0 && l.DueDate <= timestamp {
+		return fmt.Errorf("share link expired")
+	}
+	if l.MaxUses > 0 && l.Uses >= l.MaxUses {
+		return fmt.Errorf("share link is used up")
+	}
+	return nil
+}
+
+// readShareLinkSecret returns the secret in the transient data.
+func readShareLinkSecret(ctx contractapi.TransactionContextInterface) (string, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", err
+	}
+	secret := string(transient[ShareLinkSecretField])
+	if len(secret) < minShareLinkSecretLength {
+		return "", fmt.Errorf("secret of share link must have at least %d characters", minShareLinkSecretLength)
+	}
+	return secret, nil
+}
+
+func shareLinkKeys(ctx contractapi.TransactionContextInterface, key, id string) (string, string, error) {
+	linkKey, err := ctx.GetStub().CreateCompositeKey(shareLinkIndex, []string{id})
+	if err != nil {
+		return "", "", err
+	}
+	directoryKey, err := ctx.GetStub().CreateCompositeKey(directoryShareLinkIndex, []string{key, id})
+	if err != nil {
+		return "", "", err
+	}
+	return linkKey, directoryKey, nil
+}
+
+func getShareLink(ctx contractapi.TransactionContextInterface, id string) (*ShareLink, error) {
+	linkKey, err := ctx.GetStub().CreateCompositeKey(shareLinkIndex, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	link := new(ShareLink)
+	if err = GetJsonState(ctx, linkKey, link); err != nil {
+		return nil, fmt.Errorf("share link doesn't exist")
+	}
+	return link, nil
+}
+
+func putShareLink(ctx contractapi.TransactionContextInterface, link *ShareLink) error {
+	linkKey, directoryKey, err := shareLinkKeys(ctx, link.Key, link.Id)
+	if err != nil {
+		return err
+	}
+	if err = PutJsonState(ctx, linkKey, link); err != nil {
+		return err
+	}
+	return PutJsonState(ctx, directoryKey, link.Id)
+}
+
+func deleteShareLink(ctx contractapi.TransactionContextInterface, link *ShareLink) error {
+	linkKey, directoryKey, err := shareLinkKeys(ctx, link.Key, link.Id)
+	if err != nil {
+		return err
+	}
+	if err = ctx.GetStub().DelState(linkKey); err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(directoryKey)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"testing"
+)
+
+func TestShareLink_CheckRedeemable(t *testing.T) {
+	link := &ShareLink{DueDate: 200, MaxUses: 2, Uses: 1}
+	if err := link.CheckRedeemable(100); err != nil {
+		t.Errorf("should be redeemable: %v", err)
+	}
+	if err := link.CheckRedeemable(200); err == nil {
+		t.Errorf("should expire")
+	}
+	link.Uses = 2
+	if err := link.CheckRedeemable(100); err == nil {
+		t.Errorf("should be used up")
+	}
+	link = &ShareLink{}
+	if err := link.CheckRedeemable(100000); err != nil {
+		t.Errorf("link without limits should always be redeemable")
+	}
+}
+
+const testSecret = "0123456789abcdef"
+
+func (l *testLedger) withSecret(identity *testIdentity, secret string) contractapi.TransactionContextInterface {
+	ctx := l.as(identity)
+	l.must(l.stub.SetTransient(map[string][]byte{ShareLinkSecretField: []byte(secret)}))
+	return ctx
+}
+
+func TestRedeemShareLink_CreatorTermExpired(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, dave := l.user("alice"), l.user("bob"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), parent, []string{bob.ID()}, 0, l.now+100, false))
+	_, err := l.contract.CreateShareLink(l.withSecret(bob, testSecret), parent, SubscriberRole, 0, 0)
+	l.must(err)
+
+	l.now += 200
+	if _, err = l.contract.RedeemShareLink(l.withSecret(dave, testSecret)); err == nil {
+		t.Errorf("link of a cooperator whose term expired should not be redeemed")
+	}
+}
+
+func TestRedeemShareLink_SkipsForeignChildren(t *testing.T) {
+	l := newTestLedger(t)
+	alice, carol, dave := l.user("alice"), l.user("carol"), l.user("dave")
+	parent := l.directory(alice, "parent", Private)
+	victim := l.directory(carol, "victim", Private)
+
+	_, err := l.contract.AddDirectories(l.as(alice), parent, []string{victim})
+	l.must(err)
+	_, err = l.contract.CreateShareLink(l.withSecret(alice, testSecret), parent, CooperatorRole, 0, 0)
+	l.must(err)
+	_, err = l.contract.RedeemShareLink(l.withSecret(dave, testSecret))
+	l.must(err)
+
+	if !l.read(parent).IsCooperator(dave.ID()) {
+		t.Errorf("link should grant its role on its directory")
+	}
+	if l.read(victim).IsCooperator(dave.ID()) {
+		t.Errorf("link should not grant its role on a child its creator has no rights on")
+	}
+}
+
+func TestRedeemShareLink_RequiresTransientSecret(t *testing.T) {
+	l := newTestLedger(t)
+	dave := l.user("dave")
+	if _, err := l.contract.RedeemShareLink(l.withSecret(dave, "")); err == nil {
+		t.Errorf("redeeming without a transient secret should fail")
+	}
+}
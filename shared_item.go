@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	ownerIndex        = "owner"
+	sharedIndex       = "shared"
+	subscriptionIndex = "subscription"
+	sharedFileIndex   = "sharedFile"
+)
+
+const (
+	CreatorRole    = "Creator"
+	CooperatorRole = "Cooperator"
+	SubscriberRole = "Subscriber"
+)
+
+func validateRole(role string) error {
+	if role != SubscriberRole && role != CooperatorRole {
+		return fmt.Errorf("role must be %s or %s", SubscriberRole, CooperatorRole)
+	}
+	return nil
+}
+
+// RoleGrants reports whether a role given by a grant or policy includes the privilege.
+func RoleGrants(role string, privilege Privilege) bool {
+	switch privilege {
+	case Cooperator:
+		return role == CooperatorRole
+	case Subscriber:
+		return role == CooperatorRole || role == SubscriberRole
+	}
+	return true
+}
+
+// SharedItem is an entry of the per-user access index. Directory is only filled in when the entry is listed. Entries of
+// single shared files carry the file instead of the directory.
+type SharedItem struct {
+	Key       string     `json:"key"`
+	Role      string     `json:"role"`
+	DueDate   int64      `json:"dueDate"`
+	Directory *Directory `json:"directory"`
+	FileName  string     `json:"fileName,omitempty"`
+	File      *FileMeta  `json:"file,omitempty"`
+}
+
+func putIndexEntry(ctx contractapi.TransactionContextInterface, indexKey string, item *SharedItem, create bool) error {
+	bytes, err := ctx.GetStub().GetState(indexKey)
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		if len(bytes) == 0 {
+			return nil
+		}
+		return ctx.GetStub().DelState(indexKey)
+	}
+	if !create && len(bytes) == 0 {
+		return nil
+	}
+	return PutJsonState(ctx, indexKey, item)
+}
+
+// updateAccessIndex synchronizes the shared and subscription index of the given users with the access list of directory.
+// Entries are only created when create is set, otherwise existing entries are updated or removed.
+func updateAccessIndex(ctx contractapi.TransactionContextInterface, key string, directory *Directory, ids []string, create bool) error {
+	for _, id := range ids {
+		sharedKey, err := ctx.GetStub().CreateCompositeKey(sharedIndex, []string{id, key})
+		if err != nil {
+			return err
+		}
+		var shared *SharedItem
+		if directory.IsCooperator(id) && !directory.IsCreator(id) {
+			shared = &SharedItem{Key: key, Role: CooperatorRole}
+			if term, ok := directory.CooperatorTerms[id]; ok {
+				shared.DueDate = term.DueDate
+			}
+		}
+		if err = putIndexEntry(ctx, sharedKey, shared, create); err != nil {
+			return err
+		}
+
+		subscriptionKey, err := ctx.GetStub().CreateCompositeKey(subscriptionIndex, []string{id, key})
+		if err != nil {
+			return err
+		}
+		var subscription *SharedItem
+		if meta := directory.GetSubscriber(id); meta != nil {
+			subscription = &SharedItem{Key: key, Role: SubscriberRole, DueDate: meta.DueDate}
+		}
+		if err = putIndexEntry(ctx, subscriptionKey, subscription, create); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listSharedItems returns the entries of an index for a user. Entries whose directory is gone or which no longer pass
+// the check against the current access list are skipped.
+func listSharedItems(ctx contractapi.TransactionContextInterface, index, id string, check func(directory *Directory, item *SharedItem) bool) ([]*SharedItem, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	items := make([]*SharedItem, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		item := new(SharedItem)
+		if err = json.Unmarshal(kv.GetValue(), item); err != nil {
+			return nil, err
+		}
+		directory, err := getDirectory(ctx, item.Key)
+		if err != nil {
+			continue
+		}
+		if !check(directory, item) {
+			continue
+		}
+		item.Directory = directory
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func updateOwnerIndex(ctx contractapi.TransactionContextInterface, key string, directory *Directory) error {
+	ownerKey, err := ctx.GetStub().CreateCompositeKey(ownerIndex, []string{directory.Creator, key})
+	if err != nil {
+		return err
+	}
+	return putIndexEntry(ctx, ownerKey, &SharedItem{Key: key, Role: CreatorRole}, true)
+}
+
+func removeOwnerIndex(ctx contractapi.TransactionContextInterface, key string, creator string) error {
+	ownerKey, err := ctx.GetStub().CreateCompositeKey(ownerIndex, []string{creator, key})
+	if err != nil {
+		return err
+	}
+	return putIndexEntry(ctx, ownerKey, nil, false)
+}
+
+// listManagedDirectories returns the directories the user created or cooperates on.
+func listManagedDirectories(ctx contractapi.TransactionContextInterface, id string) ([]*SharedItem, error) {
+	owned, err := listSharedItems(ctx, ownerIndex, id, func(directory *Directory, item *SharedItem) bool {
+		return directory.IsCreator(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	shared, err := listSharedItems(ctx, sharedIndex, id, func(directory *Directory, item *SharedItem) bool {
+		return directory.IsCooperator(id) && !directory.IsCreator(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(owned, shared...), nil
+}
+
+// updateFileShareIndex synchronizes the shared file index of the given users with the file shares of directory.
+func updateFileShareIndex(ctx contractapi.TransactionContextInterface, key string, directory *Directory, name string, ids []string) error {
+	for _, id := range ids {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(sharedFileIndex, []string{id, key, name})
+		if err != nil {
+			return err
+		}
+		var item *SharedItem
+		if directory.IsFileSharedWith(name, id) {
+			item = &SharedItem{Key: key, Role: SubscriberRole, FileName: name}
+		}
+		if err = putIndexEntry(ctx, indexKey, item, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listSharedFiles returns the files shared with a user or group, without their directories.
+func listSharedFiles(ctx contractapi.TransactionContextInterface, id string) ([]*SharedItem, error) {
+	items, err := listSharedItems(ctx, sharedFileIndex, id, func(directory *Directory, item *SharedItem) bool {
+		item.File = directory.GetFile(item.FileName)
+		return item.File != nil && directory.IsFileSharedWith(item.FileName, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		item.Directory = nil
+	}
+	return items, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// accessEntry reads the entry of the access index of a user for a directory, nil if there is none.
+func (l *testLedger) accessEntry(index, id, key string) *SharedItem {
+	ctx := l.as(newTestIdentity("reader"))
+	indexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{id, key})
+	l.must(err)
+	bytes, err := ctx.GetStub().GetState(indexKey)
+	l.must(err)
+	if len(bytes) == 0 {
+		return nil
+	}
+	item := new(SharedItem)
+	l.must(json.Unmarshal(bytes, item))
+	return item
+}
+
+func TestListSharedWithMe(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	key := l.directory(alice, "reports", Private)
+
+	l.must(l.contract.AddCooperatorsWithTerm(l.as(alice), key, []string{bob.ID()}, 0, l.now+1000, false))
+	if entry := l.accessEntry(sharedIndex, bob.ID(), key); entry == nil || entry.Role != CooperatorRole || entry.DueDate != l.now+1000 {
+		t.Errorf("grant should create an index entry with role and due date, got %+v", entry)
+	}
+	if entry := l.accessEntry(sharedIndex, alice.ID(), key); entry != nil {
+		t.Errorf("creator should not be indexed as cooperator, got %+v", entry)
+	}
+	items, err := l.contract.ListSharedWithMe(l.as(bob))
+	l.must(err)
+	if len(items) != 1 || items[0].Key != key || items[0].Role != CooperatorRole || items[0].DueDate != l.now+1000 || items[0].Directory == nil {
+		t.Errorf("listing should return the directory with role and due date, got %v", items)
+	}
+
+	l.must(l.contract.RemoveCooperators(l.as(alice), key, []string{bob.ID()}, false))
+	if entry := l.accessEntry(sharedIndex, bob.ID(), key); entry != nil {
+		t.Errorf("revoke should remove the index entry, got %+v", entry)
+	}
+	items, err = l.contract.ListSharedWithMe(l.as(bob))
+	l.must(err)
+	if len(items) != 0 {
+		t.Errorf("revoked directory should not be listed, got %v", items)
+	}
+}
+
+func TestListSubscriptions(t *testing.T) {
+	l := newTestLedger(t)
+	alice, carol := l.user("alice"), l.user("carol")
+	key := l.directory(alice, "reports", Private)
+
+	l.must(l.contract.AddSubscribersWithTerm(l.as(alice), key, []string{carol.ID()}, false, 0, l.now+500))
+	if entry := l.accessEntry(subscriptionIndex, carol.ID(), key); entry == nil || entry.Role != SubscriberRole || entry.DueDate != l.now+500 {
+		t.Errorf("grant should create an index entry with role and due date, got %+v", entry)
+	}
+	items, err := l.contract.ListSubscriptions(l.as(carol))
+	l.must(err)
+	if len(items) != 1 || items[0].Key != key || items[0].Role != SubscriberRole || items[0].DueDate != l.now+500 || items[0].Directory == nil {
+		t.Errorf("listing should return the directory with role and due date, got %v", items)
+	}
+
+	l.must(l.contract.RemoveSubscribers(l.as(alice), key, []string{carol.ID()}, false))
+	if entry := l.accessEntry(subscriptionIndex, carol.ID(), key); entry != nil {
+		t.Errorf("revoke should remove the index entry, got %+v", entry)
+	}
+
+	l.must(l.contract.AddSubscribersWithTerm(l.as(alice), key, []string{carol.ID()}, false, 0, l.now+100))
+	l.now += 200
+	items, err = l.contract.ListSubscriptions(l.as(carol))
+	l.must(err)
+	if len(items) != 0 {
+		t.Errorf("expired subscription should not be listed, got %v", items)
+	}
+	if l.accessEntry(subscriptionIndex, carol.ID(), key) == nil {
+		t.Fatalf("expired subscription should stay indexed until pruned")
+	}
+	pruned, err := l.contract.PruneExpiredSubscribers(l.as(alice), key, false)
+	l.must(err)
+	if len(pruned) != 1 {
+		t.Errorf("expired subscriber should be pruned, got %v", pruned)
+	}
+	if entry := l.accessEntry(subscriptionIndex, carol.ID(), key); entry != nil {
+		t.Errorf("prune should remove the index entry, got %+v", entry)
+	}
+}
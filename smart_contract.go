@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"sort"
+	"strings"
 )
 
 const validity = 315532800
@@ -17,69 +19,143 @@ func (s *SmartContract) Ping(_ contractapi.TransactionContextInterface) (string,
 	return "pong", nil
 }
 
-func iteration(ctx contractapi.TransactionContextInterface, sourceDirKey, destinationDirKey string, creatorID, creatorName string, timestamp int64) error {
+type copyState struct {
+	options     CopyOptions
+	creatorID   string
+	creatorName string
+	timestamp   int64
+	destination string
+	destDir     *Directory
+	index       int
+	keys        map[string]string
+	ancestors   map[string]bool
+}
 
+func copyIteration(ctx contractapi.TransactionContextInterface, sourceDirKey string, depth int, state *copyState) (string, error) {
 	sourceDir, err := getDirectory(ctx, sourceDirKey)
 	if err != nil {
-		return err
+		return "", err
 	}
 	ok, err := sourceDir.CheckPrivilege(ctx, Subscriber)
 	if err != nil {
-		return err
+		return "", err
 	}
-	if !ok && sourceDir.Visibility != Public {
-		return privilegeError
+	if !ok && !sourceDir.IsReadableByKey() {
+		return "", privilegeError
 	}
 
-	destinationDir, err := getDirectory(ctx, destinationDirKey)
-	if err != nil {
-		return err
-	}
-	ok, err = destinationDir.CheckPrivilege(ctx, Cooperator)
-	if err != nil {
-		return err
-	}
-	if !ok {
-		return privilegeError
+	cloneDirKey := CalculateCopyKey(ctx.GetStub().GetTxID(), state.destination, sourceDirKey, state.index)
+	state.index++
+	if bytes, err := ctx.GetStub().GetState(cloneDirKey); err != nil {
+		return "", err
+	} else if len(bytes) > 0 {
+		return "", fmt.Errorf("directory key conflict")
 	}
 
-	cloneDir := NewDirectory(sourceDir.Name, creatorID, creatorName, sourceDir.Visibility, timestamp)
-	cloneDirKey := CalculateDirectoryKey(timestamp, creatorID, sourceDir.Name)
-	cloneDir.Files = sourceDir.Files
-
-	for _, dirKey := range sourceDir.Directories {
-		if err := iteration(ctx, dirKey, cloneDirKey, creatorID, creatorName, timestamp); err != nil {
-			return err
+	cloneDir := NewDirectory(sourceDir.Name, state.creatorID, state.creatorName, sourceDir.Visibility, state.timestamp)
+	for _, file := range sourceDir.Files {
+		cloneFile := *file
+		cloneDir.Files = append(cloneDir.Files, &cloneFile)
+	}
+	cloneDir.Usage = filesSize(cloneDir.Files)
+	cloneDir.Quota = sourceDir.Quota
+	cloneDir.Tags = append([]string{}, sourceDir.Tags...)
+	if state.options.IncludeACL {
+		cloneDir.CopyAccess(sourceDir)
+	} else {
+		cloneDir.CopyAccess(state.destDir)
+	}
+	cloneDir.AddCooperators([]string{state.creatorID}, []string{state.creatorName})
+	cloneDir.Target = sourceDir.Target
+	cloneDir.MountMode = sourceDir.MountMode
+
+	switch {
+	case state.options.Shallow:
+		cloneDir.AddDirectories(sourceDir.Directories)
+	case state.options.MaxDepth == 0 || depth < state.options.MaxDepth:
+		state.ancestors[sourceDirKey] = true
+		for _, dirKey := range sourceDir.Directories {
+			if state.ancestors[dirKey] {
+				continue
+			}
+			childKey, err := copyIteration(ctx, dirKey, depth+1, state)
+			if err != nil {
+				return "", err
+			}
+			cloneDir.AddDirectories([]string{childKey})
 		}
+		delete(state.ancestors, sourceDirKey)
 	}
 
-	destinationDir.AddDirectories([]string{cloneDirKey})
-	cloneDir.Cooperators = destinationDir.Cooperators
-	cloneDir.Subscribers = destinationDir.Subscribers
-	cloneDir.IDNameMap = destinationDir.IDNameMap
-	err = PutJsonState(ctx, cloneDirKey, cloneDir)
-	if err != nil {
-		return err
+	if _, ok := state.keys[sourceDirKey]; !ok {
+		state.keys[sourceDirKey] = cloneDirKey
+	}
+	if err = cloneDir.Save(ctx, cloneDirKey); err != nil {
+		return "", err
+	}
+	if err = indexAllTags(ctx, cloneDirKey, cloneDir); err != nil {
+		return "", err
 	}
-	return PutJsonState(ctx, destinationDirKey, destinationDir)
+	return cloneDirKey, nil
+}
+
+//CopyDirectory Copy a directory tree into destination. It returns the keys of the copies by the keys of their sources.
+func (s *SmartContract) CopyDirectory(ctx contractapi.TransactionContextInterface, source, destination string) (map[string]string, error) {
+	return s.CopyDirectoryWithOptions(ctx, source, destination, CopyOptions{})
 }
 
-func (s *SmartContract) CopyDirectory(ctx contractapi.TransactionContextInterface, source, destination string) error {
+//CopyDirectoryWithOptions Copy a directory tree into destination as configured by options. It returns the keys of the
+//copies by the keys of their sources.
+func (s *SmartContract) CopyDirectoryWithOptions(ctx contractapi.TransactionContextInterface, source, destination string, options CopyOptions) (map[string]string, error) {
+	ctx = readYourWrites(ctx)
 	id, err := getUserID(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	userProfile, err := getUserProfile(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	timestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if options.MaxDepth < 0 {
+		return nil, fmt.Errorf("maximum depth can't be negative")
+	}
+
+	destinationDir, err := getDirectory(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := destinationDir.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
 	}
 
-	err = iteration(ctx, source, destination, id, userProfile.Name, timestamp.Seconds)
-	return err
+	state := &copyState{
+		options:     options,
+		creatorID:   id,
+		creatorName: userProfile.Name,
+		timestamp:   timestamp.Seconds,
+		destination: destination,
+		destDir:     destinationDir,
+		keys:        make(map[string]string),
+		ancestors:   make(map[string]bool),
+	}
+	cloneDirKey, err := copyIteration(ctx, source, 1, state)
+	if err != nil {
+		return nil, err
+	}
+
+	destinationDir.AddDirectories([]string{cloneDirKey})
+	if err = destinationDir.Save(ctx, destination); err != nil {
+		return nil, err
+	}
+	return state.keys, nil
 }
 
 //RemoveFile Remove file from directory. It will return an updated directory or an error.
@@ -96,11 +172,32 @@ func (s *SmartContract) RemoveFile(ctx contractapi.TransactionContextInterface,
 		return nil, privilegeError
 	}
 
+	if err = prepareWrite(ctx, key, directory); err != nil {
+		return nil, err
+	}
+	shares := make(map[string][]string)
+	tags := make(map[string][]string)
+	for _, name := range file {
+		shares[name] = directory.FileShares[name]
+		if meta := directory.GetFile(name); meta != nil {
+			tags[name] = meta.Tags
+		}
+	}
 	directory.RemoveFiles(file)
 
 	if err = directory.Save(ctx, key); err != nil {
 		return nil, err
 	}
+	for name, ids := range shares {
+		if err = updateFileShareIndex(ctx, key, directory, name, ids); err != nil {
+			return nil, err
+		}
+	}
+	for name, fileTags := range tags {
+		if err = updateTagIndex(ctx, key, directory, name, fileTags); err != nil {
+			return nil, err
+		}
+	}
 
 	return directory, nil
 }
@@ -117,14 +214,23 @@ func (s *SmartContract) InitiateUserProfile(ctx contractapi.TransactionContextIn
 	bytes, _ := ctx.GetStub().GetState(id)
 	if len(bytes) == 0 {
 
-		privateFolder := NewDirectory("All Files", id, name, "Private", timestamp.Seconds)
-		privateFolderKey := CalculateDirectoryKey(timestamp.Seconds, id, "All Files")
+		privateFolder := NewDirectory("All Files", id, name, Private, timestamp.Seconds)
+		privateFolderKey, err := newDirectoryKey(ctx, id, "All Files")
+		if err != nil {
+			return nil, err
+		}
 
-		shareFolder := NewDirectory("Share", id, name, "Private", timestamp.Seconds)
-		shareFolderKey := CalculateDirectoryKey(timestamp.Seconds, id, "Share")
+		shareFolder := NewDirectory("Share", id, name, Private, timestamp.Seconds)
+		shareFolderKey, err := newDirectoryKey(ctx, id, "Share")
+		if err != nil {
+			return nil, err
+		}
 
-		subscriptionFolder := NewDirectory("Subscription", id, name, "Private", timestamp.Seconds)
-		subscriptionFolderKey := CalculateDirectoryKey(timestamp.Seconds, id, "Subscription")
+		subscriptionFolder := NewDirectory("Subscription", id, name, Private, timestamp.Seconds)
+		subscriptionFolderKey, err := newDirectoryKey(ctx, id, "Subscription")
+		if err != nil {
+			return nil, err
+		}
 
 		privateFolder.Directories = []string{shareFolderKey, subscriptionFolderKey}
 
@@ -139,11 +245,11 @@ func (s *SmartContract) InitiateUserProfile(ctx contractapi.TransactionContextIn
 		}
 
 		profile := &UserProfile{
-			Id:      id,
-			Name:    name,
-			Private: privateFolderKey,
-			//Share:         shareFolderKey,
-			//Subscriptions: subscriptionFolderKey,
+			Id:            id,
+			Name:          name,
+			Private:       privateFolderKey,
+			Share:         shareFolderKey,
+			Subscriptions: subscriptionFolderKey,
 		}
 
 		profileValue, _ := json.Marshal(profile)
@@ -199,7 +305,10 @@ func (s *SmartContract) ReadDirectories(ctx contractapi.TransactionContextInterf
 		if err != nil {
 			continue
 		}
-		if !ok && directory.Visibility != Public {
+		if !ok && !directory.IsReadableByKey() {
+			continue
+		}
+		if directory, err = resolveMount(ctx, directory); err != nil {
 			continue
 		}
 
@@ -217,10 +326,10 @@ func (s *SmartContract) ReadDirectory(ctx contractapi.TransactionContextInterfac
 	if err != nil {
 		return nil, err
 	}
-	if !ok && directory.Visibility != Public {
+	if !ok && !directory.IsReadableByKey() {
 		return nil, privilegeError
 	}
-	return directory, nil
+	return resolveMount(ctx, directory)
 }
 
 func (s *SmartContract) AddDirectories(ctx contractapi.TransactionContextInterface, parentKey string, newDireKeys []string) (*Directory, error) {
@@ -235,6 +344,9 @@ func (s *SmartContract) AddDirectories(ctx contractapi.TransactionContextInterfa
 	if !ok {
 		return nil, privilegeError
 	}
+	if err = prepareWrite(ctx, parentKey, directory); err != nil {
+		return nil, err
+	}
 
 	newDirs, err := s.ReadDirectories(ctx, newDireKeys)
 	children, err := s.ReadDirectories(ctx, directory.Directories)
@@ -272,6 +384,9 @@ func (s *SmartContract) RemoveDirectories(ctx contractapi.TransactionContextInte
 		return nil, privilegeError
 	}
 
+	if err = prepareWrite(ctx, parentKey, directory); err != nil {
+		return nil, err
+	}
 	directory.RemoveDirectories(childrenKeys)
 	if err = directory.Save(ctx, parentKey); err != nil {
 		return nil, err
@@ -280,6 +395,120 @@ func (s *SmartContract) RemoveDirectories(ctx contractapi.TransactionContextInte
 	return directory, nil
 }
 
+//DeleteDirectory Delete a directory and detach it from its parents. With recursive, every descendant which can't be
+//reached through another parent is deleted as well, otherwise the directory must be empty. The caller must be an active
+//cooperator of every deleted directory, and its creator or a manager. The root directories of a user are never deleted.
+//With reportOnly nothing is changed. It returns the keys of the deleted directories.
+func (s *SmartContract) DeleteDirectory(ctx contractapi.TransactionContextInterface, key string, recursive bool, reportOnly bool) ([]string, error) {
+	ctx = readYourWrites(ctx)
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	root, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !recursive && len(root.Directories) > 0 {
+		return nil, fmt.Errorf("directory isn't empty")
+	}
+
+	directories := map[string]*Directory{key: root}
+	order := []string{key}
+	for i := 0; recursive && i < len(order); i++ {
+		for _, child := range directories[order[i]].Directories {
+			if _, ok := directories[child]; ok {
+				continue
+			}
+			directory, err := getDirectory(ctx, child)
+			if err != nil {
+				continue
+			}
+			directories[child] = directory
+			order = append(order, child)
+		}
+	}
+	parents := make(map[string][]string)
+	for _, dirKey := range order {
+		if parents[dirKey], err = listParents(ctx, dirKey); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted := unreachableDirectories(key, order, parents)
+	record := make(map[string]bool)
+	profiles := make(map[string]*UserProfile)
+	for _, dirKey := range deleted {
+		directory := directories[dirKey]
+		profile, ok := profiles[directory.Creator]
+		if !ok {
+			if profile, err = getUserProfile(ctx, directory.Creator); err != nil {
+				return nil, err
+			}
+			profiles[directory.Creator] = profile
+		}
+		if dirKey == profile.Private || dirKey == profile.Share || dirKey == profile.Subscriptions {
+			return nil, protectedRootError
+		}
+		if directory.Rank(id) < ManagerRank {
+			return nil, privilegeError
+		}
+		ok, err = directory.CheckPrivilege(ctx, Cooperator)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, privilegeError
+		}
+		record[dirKey] = true
+	}
+	if reportOnly {
+		return deleted, nil
+	}
+
+	for _, parentKey := range parents[key] {
+		if record[parentKey] {
+			continue
+		}
+		parent, err := getDirectory(ctx, parentKey)
+		if err != nil {
+			continue
+		}
+		parent.RemoveDirectories([]string{key})
+		if err = parent.Save(ctx, parentKey); err != nil {
+			return nil, err
+		}
+	}
+	for _, dirKey := range deleted {
+		if err = ctx.GetStub().DelState(dirKey); err != nil {
+			return nil, err
+		}
+		if err = chargeUsage(ctx, directories[dirKey].Creator, -directories[dirKey].Usage); err != nil {
+			return nil, err
+		}
+		if err = removeDirectoryIndexes(ctx, dirKey, directories[dirKey], parents[dirKey]); err != nil {
+			return nil, err
+		}
+	}
+	return deleted, nil
+}
+
+//ReindexParents Index the children of the directories as their children, for trees saved before the parent index
+//existed. DeleteDirectory keeps a directory whose parents aren't indexed, so a directory listed by both an indexed and an
+//un-indexed parent is only deleted correctly once the latter has been indexed.
+func (s *SmartContract) ReindexParents(ctx contractapi.TransactionContextInterface, keys []string) error {
+	for _, key := range keys {
+		directory, err := getDirectory(ctx, key)
+		if err != nil {
+			return err
+		}
+		if err = updateParentIndex(ctx, key, nil, directory.Directories); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *SmartContract) RenameDirectory(ctx contractapi.TransactionContextInterface, key string, name string) (*Directory, error) {
 	directory, err := getDirectory(ctx, key)
 	if err != nil {
@@ -314,40 +543,39 @@ func (s *SmartContract) AddFile(ctx contractapi.TransactionContextInterface, key
 		return nil, privilegeError
 	}
 
-	directory.AddFiles(files)
-	if err = directory.Save(ctx, key); err != nil {
+	id, err := getUserID(ctx)
+	if err != nil {
 		return nil, err
 	}
-
-	return directory, nil
-}
-
-func (s *SmartContract) CreateDirectory(ctx contractapi.TransactionContextInterface, name string, visibility string) (string, error) {
-	creatorID, err := getUserID(ctx)
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	creatorName, err := s.ReadUserName(ctx, creatorID)
-	if err != nil {
-		return "", err
+	for _, file := range files {
+		if err = file.Validate(); err != nil {
+			return nil, err
+		}
+		file.Stamp(id, timestamp.Seconds)
 	}
-
-	timestamp, err := ctx.GetStub().GetTxTimestamp()
-	if err != nil {
-		return "", err
+	if err = prepareWrite(ctx, key, directory); err != nil {
+		return nil, err
 	}
-
-	directory := NewDirectory(name, creatorID, creatorName, visibility, timestamp.Seconds)
-	key := CalculateDirectoryKey(timestamp.Seconds, creatorID, name)
-
+	directory.AddFiles(files)
 	if err = directory.Save(ctx, key); err != nil {
-		return "", err
+		return nil, err
+	}
+	for _, file := range files {
+		if err = updateTagIndex(ctx, key, directory, file.Name, file.Tags); err != nil {
+			return nil, err
+		}
 	}
 
-	return key, nil
+	return directory, nil
 }
 
-func (s *SmartContract) SetDirectoryVisibility(ctx contractapi.TransactionContextInterface, key string, visibility string) (*Directory, error) {
+//UpdateFile Replace the content, mime type, hash, description and attributes of a file. It will return an updated
+//directory or an error.
+func (s *SmartContract) UpdateFile(ctx contractapi.TransactionContextInterface, key string, fileName string, update *FileMeta) (*Directory, error) {
 	directory, err := getDirectory(ctx, key)
 	if err != nil {
 		return nil, err
@@ -360,93 +588,1599 @@ func (s *SmartContract) SetDirectoryVisibility(ctx contractapi.TransactionContex
 		return nil, privilegeError
 	}
 
-	directory.Visibility = visibility
-	if err = directory.Save(ctx, key); err != nil {
+	update.Name = fileName
+	if err = update.Validate(); err != nil {
 		return nil, err
 	}
-	return directory, nil
-}
-
-func (s *SmartContract) ReadDirectoryHistory(ctx contractapi.TransactionContextInterface, key string) ([]*Directory, error) {
-	directory, err := getDirectory(ctx, key)
+	id, err := getUserID(ctx)
 	if err != nil {
 		return nil, err
 	}
-	ok, err := directory.CheckPrivilege(ctx, Subscriber)
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return nil, err
 	}
-	if !ok && directory.Visibility != Public {
-		return nil, privilegeError
+	if err = prepareWrite(ctx, key, directory); err != nil {
+		return nil, err
+	}
+	if err = directory.UpdateFile(fileName, update, id, timestamp.Seconds); err != nil {
+		return nil, err
+	}
+	if err = directory.Save(ctx, key); err != nil {
+		return nil, err
 	}
 
-	iterator, err := ctx.GetStub().GetHistoryForKey(key)
+	return directory, nil
+}
+
+//CreateDirectory Create a directory. Unless parentKey is empty, the directory is attached to the parent, and with
+//inheritAccess it takes over the cooperators and subscribers of the parent. It returns the key of the directory.
+func (s *SmartContract) CreateDirectory(ctx contractapi.TransactionContextInterface, name string, visibility string, parentKey string, inheritAccess bool) (string, error) {
+	if err := ValidateVisibility(visibility); err != nil {
+		return "", err
+	}
+	if parentKey == "" && inheritAccess {
+		return "", fmt.Errorf("can't inherit access without a parent")
+	}
+	creatorID, err := getUserID(ctx)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	creatorName, err := s.ReadUserName(ctx, creatorID)
+	if err != nil {
+		return "", err
 	}
 
-	dirs := make([]*Directory, 0)
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
 
-	for iterator.HasNext() {
-		mod, err := iterator.Next()
+	var parent *Directory
+	if parentKey != "" {
+		if parent, err = getDirectory(ctx, parentKey); err != nil {
+			return "", err
+		}
+		ok, err := parent.CheckPrivilege(ctx, Cooperator)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		dir := new(Directory)
-		if err = json.Unmarshal(mod.GetValue(), dir); err != nil {
-			return nil, err
+		if !ok {
+			return "", privilegeError
+		}
+		if err = prepareWrite(ctx, parentKey, parent); err != nil {
+			return "", err
+		}
+		if err = s.checkChildName(ctx, parent, name); err != nil {
+			return "", err
 		}
-		dirs = append(dirs, dir)
 	}
 
-	return dirs, nil
-}
-
-func getNameByID(ctx contractapi.TransactionContextInterface, ids []string) ([]string, error) {
-	names := make([]string, len(ids))
+	directory := NewDirectory(name, creatorID, creatorName, visibility, timestamp.Seconds)
+	key, err := newDirectoryKey(ctx, creatorID, name)
+	if err != nil {
+		return "", err
+	}
+	if inheritAccess {
+		directory.CopyAccess(parent)
+		directory.AddCooperators([]string{creatorID}, []string{creatorName})
+	}
 
-	for index, id := range ids {
-		bytes, err := ctx.GetStub().GetState(id)
-		if err != nil {
-			return nil, err
-		}
-		userProle := new(UserProfile)
-		if err = json.Unmarshal(bytes, userProle); err != nil {
-			return nil, err
+	if err = directory.Save(ctx, key); err != nil {
+		return "", err
+	}
+	if parent != nil {
+		parent.AddDirectories([]string{key})
+		if err = parent.Save(ctx, parentKey); err != nil {
+			return "", err
 		}
-		names[index] = userProle.Name
 	}
 
-	return names, nil
+	return key, nil
 }
 
-type Action = func(directory *Directory, ids []string, names []string, timestamp int64)
-
-func updateDirectoryAccess(
-	ctx contractapi.TransactionContextInterface,
-	key string,
-	ids []string,
-	recursive bool,
-	action Action,
-) error {
-	timestamp, err := ctx.GetStub().GetTxTimestamp()
+// checkChildName fails if parent already has a child readable by the caller with the given name.
+func (s *SmartContract) checkChildName(ctx contractapi.TransactionContextInterface, parent *Directory, name string) error {
+	children, err := s.ReadDirectories(ctx, parent.Directories)
 	if err != nil {
 		return err
 	}
-	names, err := getNameByID(ctx, ids)
-	if err != nil {
-		return err
+	for _, child := range children {
+		if child.Name == name {
+			return fmt.Errorf("directory name conflict")
+		}
+	}
+	return nil
+}
+
+//CreateMount Place a mount of target in parent. The mount shows the content of target to whoever can read both, without
+//copying it. It returns the key of the mount.
+func (s *SmartContract) CreateMount(ctx contractapi.TransactionContextInterface, parentKey string, targetKey string, name string, mode string) (string, error) {
+	if err := ValidateMountMode(mode); err != nil {
+		return "", err
+	}
+	creatorID, err := getUserID(ctx)
+	if err != nil {
+		return "", err
+	}
+	creatorName, err := s.ReadUserName(ctx, creatorID)
+	if err != nil {
+		return "", err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+
+	parent, err := getDirectory(ctx, parentKey)
+	if err != nil {
+		return "", err
+	}
+	ok, err := parent.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", privilegeError
+	}
+	if err = prepareWrite(ctx, parentKey, parent); err != nil {
+		return "", err
+	}
+
+	target, err := getDirectory(ctx, targetKey)
+	if err != nil {
+		return "", err
+	}
+	if target.IsMount() {
+		targetKey = target.Target
+	}
+	mount := NewDirectory(name, creatorID, creatorName, parent.Visibility, timestamp.Seconds)
+	mount.Target = targetKey
+	mount.MountMode = mode
+	if _, err = getMountTarget(ctx, mount); err != nil {
+		return "", err
+	}
+
+	if err = s.checkChildName(ctx, parent, name); err != nil {
+		return "", err
+	}
+
+	key := CalculateCopyKey(ctx.GetStub().GetTxID(), parentKey, targetKey, 0)
+	if bytes, err := ctx.GetStub().GetState(key); err != nil {
+		return "", err
+	} else if len(bytes) > 0 {
+		return "", fmt.Errorf("directory key conflict")
+	}
+	mount.CopyAccess(parent)
+	mount.AddCooperators([]string{creatorID}, []string{creatorName})
+	if err = mount.Save(ctx, key); err != nil {
+		return "", err
+	}
+
+	parent.AddDirectories([]string{key})
+	if err = parent.Save(ctx, parentKey); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *SmartContract) SetDirectoryVisibility(ctx contractapi.TransactionContextInterface, key string, visibility string) (*Directory, error) {
+	if err := ValidateVisibility(visibility); err != nil {
+		return nil, err
+	}
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
+	}
+
+	directory.Visibility = visibility
+	if err = directory.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	return directory, nil
+}
+
+func (s *SmartContract) ReadDirectoryHistory(ctx contractapi.TransactionContextInterface, key string) ([]*Directory, error) {
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Subscriber)
+	if err != nil {
+		return nil, err
+	}
+	if !ok && !directory.IsReadableByKey() {
+		return nil, privilegeError
+	}
+
+	iterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]*Directory, 0)
+
+	for iterator.HasNext() {
+		mod, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		dir := new(Directory)
+		if err = json.Unmarshal(mod.GetValue(), dir); err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, dir)
+	}
+
+	return dirs, nil
+}
+
+func getNameByID(ctx contractapi.TransactionContextInterface, ids []string) ([]string, error) {
+	names := make([]string, len(ids))
+
+	for index, id := range ids {
+		if IsGroupReference(id) {
+			group, err := getGroup(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			names[index] = group.Name
+			continue
+		}
+		bytes, err := ctx.GetStub().GetState(id)
+		if err != nil {
+			return nil, err
+		}
+		userProle := new(UserProfile)
+		if err = json.Unmarshal(bytes, userProle); err != nil {
+			return nil, err
+		}
+		names[index] = userProle.Name
+	}
+
+	return names, nil
+}
+
+type Action = func(directory *Directory, ids []string, names []string, timestamp int64) error
+
+// Authorize decides whether an access update may be applied to a directory. It returns skipDirectoryError to leave the
+// directory and its children out of the update without failing.
+type Authorize = func(directory *Directory, timestamp int64) error
+
+var skipDirectoryError = fmt.Errorf("directory is skipped")
+
+// callerAuthorizer requires the caller to hold privilege on every directory of the update through the access lists,
+// directly or through a group. Rights from organization grants and access policies only last while the identity of the
+// caller matches them, so they don't allow handing out access that would outlast them.
+func callerAuthorizer(ctx contractapi.TransactionContextInterface, privilege Privilege) Authorize {
+	return func(directory *Directory, timestamp int64) error {
+		id, err := getUserID(ctx)
+		if err != nil {
+			return err
+		}
+		ok, err := directory.PrincipalHasPrivilege(ctx, id, privilege, timestamp)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return privilegeError
+		}
+		return nil
+	}
+}
+
+// grantorAuthorizer requires the user on whose behalf access is granted to be an active cooperator. Children the
+// grantor has no rights on are skipped, so that nobody gains access through a directory attached by someone else.
+func grantorAuthorizer(ctx contractapi.TransactionContextInterface, grantor string) Authorize {
+	return func(directory *Directory, timestamp int64) error {
+		ok, err := directory.PrincipalHasPrivilege(ctx, grantor, Cooperator, timestamp)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return skipDirectoryError
+		}
+		return nil
+	}
+}
+
+// readableAuthorizer lets anyone subscribe to public and unlisted directories on behalf of grantor, usually the creator
+// of the subscribed directory. Private children and children the grantor has no rights on are skipped.
+func readableAuthorizer(ctx contractapi.TransactionContextInterface, grantor string) Authorize {
+	authorize := grantorAuthorizer(ctx, grantor)
+	return func(directory *Directory, timestamp int64) error {
+		if !directory.IsReadableByKey() {
+			return skipDirectoryError
+		}
+		return authorize(directory, timestamp)
+	}
+}
+
+func updateDirectoryAccess(
+	ctx contractapi.TransactionContextInterface,
+	key string,
+	ids []string,
+	recursive bool,
+	action Action,
+) error {
+	return applyDirectoryAccess(ctx, key, ids, recursive, callerAuthorizer(ctx, Cooperator), action)
+}
+
+//grantDirectoryAccess Apply action on behalf of grantor instead of the caller. Directories the grantor isn't an active
+//cooperator of are left out. Only use it in transactions which authorized the caller in another way.
+func grantDirectoryAccess(
+	ctx contractapi.TransactionContextInterface,
+	key string,
+	ids []string,
+	recursive bool,
+	grantor string,
+	action Action,
+) error {
+	return applyDirectoryAccess(ctx, key, ids, recursive, grantorAuthorizer(ctx, grantor), action)
+}
+
+func applyDirectoryAccess(
+	ctx contractapi.TransactionContextInterface,
+	key string,
+	ids []string,
+	recursive bool,
+	authorize Authorize,
+	action Action,
+) error {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	names, err := getNameByID(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return updateIteration(ctx, key, ids, names, timestamp.Seconds, recursive, true, authorize, action)
+}
+
+func updateIteration(ctx contractapi.TransactionContextInterface, dirKey string, ids, names []string, timestamp int64, recursive bool, root bool, authorize Authorize, action Action) error {
+	dir, err := getDirectory(ctx, dirKey)
+	if err != nil {
+		return err
+	}
+	if err = authorize(dir, timestamp); err == skipDirectoryError {
+		if root {
+			return privilegeError
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err = action(dir, ids, names, timestamp); err != nil {
+		return err
+	}
+	if err = dir.Save(ctx, dirKey); err != nil {
+		return err
+	}
+	if err = updateAccessIndex(ctx, dirKey, dir, ids, root); err != nil {
+		return err
+	}
+	if recursive {
+		for _, dirKey := range dir.Directories {
+			err = updateIteration(ctx, dirKey, ids, names, timestamp, recursive, false, authorize, action)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SmartContract) AddSubscribers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
+	return s.AddSubscribersWithTerm(ctx, key, ids, recursive, 0, 0)
+}
+
+//AddSubscribersWithTerm Add subscribers for duration seconds or until the absolute dueDate. If both are zero the
+//default term of each directory is used.
+func (s *SmartContract) AddSubscribersWithTerm(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool, duration int64, dueDate int64) error {
+	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		date, err := directory.SubscriptionDueDate(timestamp, duration, dueDate)
+		if err != nil {
+			return err
+		}
+		directory.AddSubscribers(ids, names, date)
+		return nil
+	})
+}
+
+//RenewSubscription Extend the subscriptions of existing subscribers by duration seconds, or by the default term of
+//each directory if duration is zero.
+func (s *SmartContract) RenewSubscription(ctx contractapi.TransactionContextInterface, key string, ids []string, duration int64, recursive bool) error {
+	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		return directory.RenewSubscribers(ids, duration, timestamp)
+	})
+}
+
+//SetSubscriptionTerms Set the default and maximum subscription term of a directory in seconds. Zero means unset.
+func (s *SmartContract) SetSubscriptionTerms(ctx contractapi.TransactionContextInterface, key string, defaultTerm int64, maxTerm int64) (*Directory, error) {
+	if defaultTerm < 0 || maxTerm < 0 {
+		return nil, fmt.Errorf("subscription term can't be negative")
+	}
+	if maxTerm > 0 && defaultTerm > maxTerm {
+		return nil, fmt.Errorf("default subscription term exceeds the maximum term")
+	}
+
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
+	}
+
+	directory.DefaultSubscriptionTerm = defaultTerm
+	directory.MaxSubscriptionTerm = maxTerm
+	if err = directory.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	return directory, nil
+}
+
+func (s *SmartContract) AddCooperators(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
+	return s.AddCooperatorsWithTerm(ctx, key, ids, 0, 0, recursive)
+}
+
+//AddCooperatorsWithTerm Add cooperators whose grant is only effective from startDate until dueDate. A zero date
+//leaves that side open, so two zero dates grant permanent access.
+func (s *SmartContract) AddCooperatorsWithTerm(ctx contractapi.TransactionContextInterface, key string, ids []string, startDate int64, dueDate int64, recursive bool) error {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if startDate < 0 || dueDate < 0 {
+		return fmt.Errorf("date can't be negative")
+	}
+	if dueDate > 0 && (dueDate <= startDate || dueDate <= timestamp.Seconds) {
+		return fmt.Errorf("due date must be after the start date and in the future")
+	}
+
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		limit, err := directory.GrantDueDate(ctx, id, timestamp)
+		if err != nil {
+			return err
+		}
+		return directory.GrantCooperators(id, ids, names, startDate, dueDate, limit)
+	})
+}
+
+func (s *SmartContract) RemoveSubscribers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
+	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		directory.RemoveSubscribers(ids)
+		return nil
+	})
+}
+
+func (s *SmartContract) RemoveCooperators(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		if err := directory.CheckRemoval(id, ids); err != nil {
+			return err
+		}
+		directory.RemoveCooperators(ids)
+		return nil
+	})
+}
+
+//PromoteManagers Raise cooperators to the manager rank. Only managers and the creator can promote.
+func (s *SmartContract) PromoteManagers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		if directory.Rank(id) < ManagerRank {
+			return privilegeError
+		}
+		directory.PromoteManagers(ids)
+		return nil
+	})
+}
+
+//DemoteManagers Lower managers to the cooperator rank. The same protection rules as for RemoveCooperators apply.
+func (s *SmartContract) DemoteManagers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		if err := directory.CheckRemoval(id, ids); err != nil {
+			return err
+		}
+		directory.DemoteManagers(ids)
+		return nil
+	})
+}
+
+//Subscribe Subscribe the caller to a directory and its children. Anyone can subscribe to public and unlisted
+//directories, which covers the children readable by key that the creator of the directory cooperates on.
+func (s *SmartContract) Subscribe(ctx contractapi.TransactionContextInterface, key string) (*Directory, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{id}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if directory.IsSubscribers(id, timestamp.Seconds) {
+		return directory, nil
+	}
+
+	if directory.IsCreator(id) || directory.IsCooperator(id) {
+		err = s.AddSubscribers(ctx, key, ids, true)
+	} else if directory.IsReadableByKey() {
+		err = applyDirectoryAccess(ctx, key, ids, true, readableAuthorizer(ctx, directory.Creator), func(directory *Directory, ids []string, names []string, timestamp int64) error {
+			date, err := directory.SubscriptionDueDate(timestamp, 0, 0)
+			if err != nil {
+				return err
+			}
+			directory.AddSubscribers(ids, names, date)
+			return nil
+		})
+	} else {
+		return nil, fmt.Errorf("can't access private directory")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return directory, nil
+}
+
+//ListSharedWithMe List the directories other users made the caller, or a group of the caller, a cooperator of, and the
+//single files shared with them.
+func (s *SmartContract) ListSharedWithMe(ctx contractapi.TransactionContextInterface) ([]*SharedItem, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	principals, err := getPrincipals(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*SharedItem, 0)
+	for _, principal := range principals {
+		shared, err := listSharedItems(ctx, sharedIndex, principal, func(directory *Directory, item *SharedItem) bool {
+			if !directory.IsActiveCooperator(principal, timestamp.Seconds) || directory.IsCreator(id) {
+				return false
+			}
+			if term, ok := directory.CooperatorTerms[principal]; ok {
+				item.DueDate = term.DueDate
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, shared...)
+
+		files, err := listSharedFiles(ctx, principal)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, files...)
+	}
+	return items, nil
+}
+
+//ListSubscriptions List the directories the caller, or a group of the caller, is subscribed to and the due date of
+//each subscription.
+func (s *SmartContract) ListSubscriptions(ctx contractapi.TransactionContextInterface) ([]*SharedItem, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	principals, err := getPrincipals(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*SharedItem, 0)
+	for _, principal := range principals {
+		subscriptions, err := listSharedItems(ctx, subscriptionIndex, principal, func(directory *Directory, item *SharedItem) bool {
+			meta := directory.GetSubscriber(principal)
+			if meta == nil || meta.DueDate <= timestamp.Seconds {
+				return false
+			}
+			item.DueDate = meta.DueDate
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, subscriptions...)
+	}
+	return items, nil
+}
+
+//RequestSubscription Ask the cooperators of a private directory for a subscription.
+func (s *SmartContract) RequestSubscription(ctx contractapi.TransactionContextInterface, key string, message string) error {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+	userProfile, err := getUserProfile(ctx, id)
+	if err != nil {
+		return err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return err
+	}
+	if directory.IsCooperator(id) || directory.IsSubscribers(id, timestamp.Seconds) {
+		return fmt.Errorf("already have access to directory")
+	}
+	if directory.IsReadableByKey() {
+		return fmt.Errorf("directory is public or unlisted, subscribe to it directly")
+	}
+
+	requestKey, err := subscriptionRequestKey(ctx, key, id)
+	if err != nil {
+		return err
+	}
+	return PutJsonState(ctx, requestKey, &SubscriptionRequest{
+		Key:           key,
+		Requester:     id,
+		RequesterName: userProfile.Name,
+		Message:       message,
+		Date:          timestamp.Seconds,
+	})
+}
+
+//ListPendingRequests List the subscription requests of a directory which are waiting for approval.
+func (s *SmartContract) ListPendingRequests(ctx contractapi.TransactionContextInterface, key string) ([]*SubscriptionRequest, error) {
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(subscriptionRequestIndex, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	requests := make([]*SubscriptionRequest, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		request := new(SubscriptionRequest)
+		if err = json.Unmarshal(kv.GetValue(), request); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+//ApproveSubscription Subscribe the requester to the directory and its children, and notify the requester.
+func (s *SmartContract) ApproveSubscription(ctx contractapi.TransactionContextInterface, key string, requester string) error {
+	requestKey, _, err := getSubscriptionRequest(ctx, key, requester)
+	if err != nil {
+		return err
+	}
+
+	if err = s.AddSubscribers(ctx, key, []string{requester}, true); err != nil {
+		return err
+	}
+	if err = ctx.GetStub().DelState(requestKey); err != nil {
+		return err
+	}
+	return SetJsonEvent(ctx, "SubscriptionApproved", &SubscriptionEvent{Key: key, Requester: requester})
+}
+
+//RejectSubscription Drop a subscription request and notify the requester.
+func (s *SmartContract) RejectSubscription(ctx contractapi.TransactionContextInterface, key string, requester string) error {
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return privilegeError
+	}
+
+	requestKey, _, err := getSubscriptionRequest(ctx, key, requester)
+	if err != nil {
+		return err
+	}
+	if err = ctx.GetStub().DelState(requestKey); err != nil {
+		return err
+	}
+	return SetJsonEvent(ctx, "SubscriptionRejected", &SubscriptionEvent{Key: key, Requester: requester})
+}
+
+func pruneIteration(ctx contractapi.TransactionContextInterface, dirKey string, timestamp int64, recursive bool, pruned map[string]bool) error {
+	dir, err := getDirectory(ctx, dirKey)
+	if err != nil {
+		return err
+	}
+	ok, err := dir.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return privilegeError
+	}
+
+	ids := dir.PruneExpiredSubscribers(timestamp)
+	if len(ids) > 0 {
+		if err = dir.Save(ctx, dirKey); err != nil {
+			return err
+		}
+		if err = updateAccessIndex(ctx, dirKey, dir, ids, false); err != nil {
+			return err
+		}
+	}
+	for _, id := range ids {
+		pruned[id] = true
+	}
+
+	if recursive {
+		for _, dirKey := range dir.Directories {
+			if err = pruneIteration(ctx, dirKey, timestamp, recursive, pruned); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//PruneExpiredSubscribers Remove expired subscriptions from a directory and return the ids of the removed subscribers.
+func (s *SmartContract) PruneExpiredSubscribers(ctx contractapi.TransactionContextInterface, key string, recursive bool) ([]string, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := make(map[string]bool)
+	if err = pruneIteration(ctx, key, timestamp.Seconds, recursive, pruned); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(pruned))
+	for id := range pruned {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+//ExpiringSubscriptions List the subscriptions of the directories managed by the caller which expire within the given
+//number of seconds.
+func (s *SmartContract) ExpiringSubscriptions(ctx contractapi.TransactionContextInterface, withinSeconds int64) ([]*ExpiringGrant, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := listManagedDirectories(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]*ExpiringGrant, 0)
+	for _, item := range items {
+		record := make(map[string]bool)
+		for _, subscriber := range item.Directory.Subscribers {
+			if record[subscriber.Id] {
+				continue
+			}
+			record[subscriber.Id] = true
+
+			dueDate := item.Directory.GetSubscriber(subscriber.Id).DueDate
+			if dueDate <= timestamp.Seconds || dueDate > timestamp.Seconds+withinSeconds {
+				continue
+			}
+			grants = append(grants, &ExpiringGrant{
+				Key:           item.Key,
+				DirectoryName: item.Directory.Name,
+				Id:            subscriber.Id,
+				Name:          item.Directory.IDNameMap[subscriber.Id],
+				Role:          SubscriberRole,
+				DueDate:       dueDate,
+			})
+		}
+	}
+	return grants, nil
+}
+
+//Mint Issue tokens to a user. Only admin identities can mint.
+func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, userID string, amount int64) (*UserProfile, error) {
+	ok, err := isAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
+	}
+
+	userProfile, err := getUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = userProfile.Credit(amount); err != nil {
+		return nil, err
+	}
+	if err = PutJsonState(ctx, userID, userProfile); err != nil {
+		return nil, err
+	}
+	return userProfile, nil
+}
+
+//Transfer Move tokens from the caller to another user.
+func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, to string, amount int64) (*UserProfile, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id == to {
+		return nil, fmt.Errorf("can't transfer to yourself")
+	}
+
+	sender, err := getUserProfile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	receiver, err := getUserProfile(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if err = sender.Debit(amount); err != nil {
+		return nil, err
+	}
+	if err = receiver.Credit(amount); err != nil {
+		return nil, err
+	}
+
+	if err = PutJsonState(ctx, to, receiver); err != nil {
+		return nil, err
+	}
+	if err = PutJsonState(ctx, id, sender); err != nil {
+		return nil, err
+	}
+	return sender, nil
+}
+
+//SetSubscriptionPrice Put a directory up for sale. Each purchase grants a subscription of period seconds, or of the
+//default term of the directory if period is zero. A price of zero takes the directory off sale.
+func (s *SmartContract) SetSubscriptionPrice(ctx contractapi.TransactionContextInterface, key string, price int64, period int64) (*Directory, error) {
+	if price < 0 || period < 0 {
+		return nil, fmt.Errorf("price and period can't be negative")
+	}
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !directory.IsCreator(id) {
+		return nil, privilegeError
+	}
+	if directory.MaxSubscriptionTerm > 0 && period > directory.MaxSubscriptionTerm {
+		return nil, subscriptionTermError
+	}
+
+	directory.SubscriptionPrice = price
+	directory.SubscriptionPeriod = period
+	if err = directory.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	return directory, nil
+}
+
+//PurchaseSubscription Pay the price of a directory to its creator and subscribe to the directory and its children for
+//one period. Children the creator isn't a cooperator of are left out. An existing subscription is extended instead.
+func (s *SmartContract) PurchaseSubscription(ctx contractapi.TransactionContextInterface, key string) (*Directory, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if directory.SubscriptionPrice <= 0 {
+		return nil, fmt.Errorf("directory is not for sale")
+	}
+	if directory.IsCooperator(id) {
+		return nil, fmt.Errorf("already have access to directory")
+	}
+
+	subscriber, err := getUserProfile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := getUserProfile(ctx, directory.Creator)
+	if err != nil {
+		return nil, err
+	}
+	if err = subscriber.Debit(directory.SubscriptionPrice); err != nil {
+		return nil, err
+	}
+	if err = creator.Credit(directory.SubscriptionPrice); err != nil {
+		return nil, err
+	}
+
+	start := timestamp.Seconds
+	if meta := directory.GetSubscriber(id); meta != nil && meta.DueDate > start {
+		start = meta.DueDate
+	}
+	dueDate, err := directory.SubscriptionDueDate(start, directory.SubscriptionPeriod, 0)
+	if err != nil {
+		return nil, err
+	}
+	if directory.MaxSubscriptionTerm > 0 && dueDate-timestamp.Seconds > directory.MaxSubscriptionTerm {
+		return nil, subscriptionTermError
+	}
+
+	err = grantDirectoryAccess(ctx, key, []string{id}, true, directory.Creator, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		directory.AddSubscribers(ids, names, dueDate)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = PutJsonState(ctx, directory.Creator, creator); err != nil {
+		return nil, err
+	}
+	if err = PutJsonState(ctx, id, subscriber); err != nil {
+		return nil, err
+	}
+
+	directory.AddSubscribers([]string{id}, []string{subscriber.Name}, dueDate)
+	err = SetJsonEvent(ctx, "SubscriptionPurchased", &SubscriptionEvent{
+		Key:       key,
+		Requester: id,
+		Price:     directory.SubscriptionPrice,
+		DueDate:   dueDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return directory, nil
+}
+
+//TransferOwnership Make another user the creator of a directory, and of the children owned by the caller if recursive
+//is set. If requireAcceptance is set the transfer is stored until the new owner accepts it.
+func (s *SmartContract) TransferOwnership(ctx contractapi.TransactionContextInterface, key string, newOwnerID string, recursive bool, requireAcceptance bool) error {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if id == newOwnerID {
+		return fmt.Errorf("already the owner of directory")
+	}
+
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !directory.IsCreator(id) {
+		return privilegeError
+	}
+	if _, err = getUserProfile(ctx, newOwnerID); err != nil {
+		return fmt.Errorf("new owner doesn't exist")
+	}
+
+	transfer := &OwnershipTransfer{
+		Key:       key,
+		From:      id,
+		To:        newOwnerID,
+		Recursive: recursive,
+		Date:      timestamp.Seconds,
+	}
+	if requireAcceptance {
+		transferKey, err := ctx.GetStub().CreateCompositeKey(ownershipTransferIndex, []string{key})
+		if err != nil {
+			return err
+		}
+		return PutJsonState(ctx, transferKey, transfer)
+	}
+	return transferOwnership(ctx, transfer)
+}
+
+//AcceptOwnership Accept a pending ownership transfer of a directory.
+func (s *SmartContract) AcceptOwnership(ctx contractapi.TransactionContextInterface, key string) error {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+	transferKey, transfer, err := getOwnershipTransfer(ctx, key)
+	if err != nil {
+		return err
+	}
+	if transfer.To != id {
+		return privilegeError
+	}
+
+	if err = ctx.GetStub().DelState(transferKey); err != nil {
+		return err
+	}
+	return transferOwnership(ctx, transfer)
+}
+
+//DeclineOwnership Drop a pending ownership transfer. Both the current and the new owner can decline it.
+func (s *SmartContract) DeclineOwnership(ctx contractapi.TransactionContextInterface, key string) error {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+	transferKey, transfer, err := getOwnershipTransfer(ctx, key)
+	if err != nil {
+		return err
+	}
+	if transfer.To != id && transfer.From != id {
+		return privilegeError
+	}
+	return ctx.GetStub().DelState(transferKey)
+}
+
+//InviteCooperator Invite a user to cooperate on a directory. The invitee doesn't need a profile yet. The invitation
+//expires after validFor seconds, or after a week if validFor is zero.
+func (s *SmartContract) InviteCooperator(ctx contractapi.TransactionContextInterface, key string, inviteeID string, validFor int64, recursive bool) (*Invitation, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userProfile, err := getUserProfile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	if validFor < 0 {
+		return nil, fmt.Errorf("validity of invitation can't be negative")
+	}
+	if validFor == 0 {
+		validFor = invitationValidity
+	}
+
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// Acceptance re-checks the inviter against the access lists, so rights from organization grants or policies, which
+	// depend on the certificate of the inviter, can't be passed on.
+	ok, err := directory.PrincipalHasPrivilege(ctx, id, Cooperator, timestamp.Seconds)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("only cooperators listed directly or through a group can invite")
+	}
+	if directory.IsCooperator(inviteeID) {
+		return nil, fmt.Errorf("invitee is already a cooperator")
+	}
+
+	invitation := &Invitation{
+		Key:           key,
+		DirectoryName: directory.Name,
+		Inviter:       id,
+		InviterName:   userProfile.Name,
+		Invitee:       inviteeID,
+		Recursive:     recursive,
+		Date:          timestamp.Seconds,
+		DueDate:       timestamp.Seconds + validFor,
+	}
+	if err = putInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+//AcceptInvitation Become a cooperator of the directory the caller was invited to.
+func (s *SmartContract) AcceptInvitation(ctx contractapi.TransactionContextInterface, key string) (*Directory, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := getInvitation(ctx, key, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.DueDate <= timestamp.Seconds {
+		return nil, fmt.Errorf("invitation expired")
+	}
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.PrincipalHasPrivilege(ctx, invitation.Inviter, Cooperator, timestamp.Seconds)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("inviter is no longer a cooperator")
+	}
+
+	name := id
+	if userProfile, err := getUserProfile(ctx, id); err == nil {
+		name = userProfile.Name
+	}
+	grant := func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		limit, err := directory.GrantDueDate(ctx, invitation.Inviter, timestamp)
+		if err != nil {
+			return err
+		}
+		return directory.AddCooperatorsUntil(ids, names, limit)
+	}
+	authorize := grantorAuthorizer(ctx, invitation.Inviter)
+	err = updateIteration(ctx, key, []string{id}, []string{name}, timestamp.Seconds, invitation.Recursive, true, authorize, grant)
+	if err != nil {
+		return nil, err
+	}
+	if err = deleteInvitation(ctx, key, id); err != nil {
+		return nil, err
+	}
+
+	if err = grant(directory, []string{id}, []string{name}, timestamp.Seconds); err != nil {
+		return nil, err
+	}
+	return directory, nil
+}
+
+//DeclineInvitation Drop an invitation sent to the caller.
+func (s *SmartContract) DeclineInvitation(ctx contractapi.TransactionContextInterface, key string) error {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err = getInvitation(ctx, key, id); err != nil {
+		return err
+	}
+	return deleteInvitation(ctx, key, id)
+}
+
+//RevokeInvitation Withdraw an invitation of a directory before it is accepted.
+func (s *SmartContract) RevokeInvitation(ctx contractapi.TransactionContextInterface, key string, inviteeID string) error {
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return privilegeError
+	}
+	if _, err = getInvitation(ctx, key, inviteeID); err != nil {
+		return err
+	}
+	return deleteInvitation(ctx, key, inviteeID)
+}
+
+//ListInvitations List the pending invitations sent to the caller.
+func (s *SmartContract) ListInvitations(ctx contractapi.TransactionContextInterface) ([]*Invitation, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	return listInvitations(ctx, invitationIndex, id, timestamp.Seconds)
+}
+
+//ListDirectoryInvitations List the pending invitations of a directory.
+func (s *SmartContract) ListDirectoryInvitations(ctx contractapi.TransactionContextInterface, key string) ([]*Invitation, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
+	}
+	return listInvitations(ctx, directoryInvitationIndex, key, timestamp.Seconds)
+}
+
+//CreateGroup Create a group owned by the caller. The group can be put into access lists by its reference, which is
+//the group id prefixed with "group:".
+func (s *SmartContract) CreateGroup(ctx contractapi.TransactionContextInterface, name string, members []string) (*Group, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	group := &Group{
+		Id:      SHA256(fmt.Sprintf("%s%s%s", ctx.GetStub().GetTxID(), id, name))[0:16],
+		Name:    name,
+		Owner:   id,
+		Members: []string{id},
+		Date:    timestamp.Seconds,
+	}
+	if _, err = getGroup(ctx, group.Id); err == nil {
+		return nil, fmt.Errorf("group already exists")
+	}
+	group.AddMembers(members)
+
+	if err = group.Save(ctx); err != nil {
+		return nil, err
+	}
+	if err = updateGroupMemberIndex(ctx, group, group.Members); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *SmartContract) ReadGroup(ctx contractapi.TransactionContextInterface, groupID string) (*Group, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	group, err := getGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !group.IsMember(id) && group.Owner != id {
+		return nil, privilegeError
+	}
+	return group, nil
+}
+
+//AddGroupMembers Add members to a group. Only the owner of the group can change its members.
+func (s *SmartContract) AddGroupMembers(ctx contractapi.TransactionContextInterface, groupID string, ids []string) (*Group, error) {
+	return updateGroupMembers(ctx, groupID, ids, func(group *Group) error {
+		group.AddMembers(ids)
+		return nil
+	})
+}
+
+//RemoveGroupMembers Remove members from a group. The owner can't be removed.
+func (s *SmartContract) RemoveGroupMembers(ctx contractapi.TransactionContextInterface, groupID string, ids []string) (*Group, error) {
+	return updateGroupMembers(ctx, groupID, ids, func(group *Group) error {
+		for _, id := range ids {
+			if id == group.Owner {
+				return fmt.Errorf("can't remove the owner of group")
+			}
+		}
+		group.RemoveMembers(ids)
+		return nil
+	})
+}
+
+func updateGroupMembers(ctx contractapi.TransactionContextInterface, groupID string, ids []string, action func(group *Group) error) (*Group, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	group, err := getGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.Owner != id {
+		return nil, privilegeError
+	}
+
+	if err = action(group); err != nil {
+		return nil, err
+	}
+	if err = group.Save(ctx); err != nil {
+		return nil, err
+	}
+	if err = updateGroupMemberIndex(ctx, group, ids); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+//GrantOrganization Give every identity of an MSP a role on a directory. Attributes optionally restrict the grant to
+//identities whose certificate has all of the given attribute values. A cooperator role granted this way covers the
+//content of the directory, but not handing out access to it.
+func (s *SmartContract) GrantOrganization(ctx contractapi.TransactionContextInterface, key string, mspID string, role string, attributes map[string]string, recursive bool) error {
+	grant, err := NewOrganizationGrant(mspID, role, attributes)
+	if err != nil {
+		return err
+	}
+	return updateDirectoryAccess(ctx, key, nil, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		directory.SetOrganizationGrant(grant)
+		return nil
+	})
+}
+
+//RevokeOrganization Remove the grant of an MSP from a directory.
+func (s *SmartContract) RevokeOrganization(ctx contractapi.TransactionContextInterface, key string, mspID string, recursive bool) error {
+	return updateDirectoryAccess(ctx, key, nil, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		directory.RemoveOrganizationGrants(mspID)
+		return nil
+	})
+}
+
+//SetAccessPolicy Grant a role to every identity of the organization whose certificate attributes satisfy the policy
+//expression. An empty expression removes the policy of the role for the organization.
+func (s *SmartContract) SetAccessPolicy(ctx contractapi.TransactionContextInterface, key string, role string, mspID string, expression string, recursive bool) error {
+	if mspID == "" {
+		return fmt.Errorf("msp id of an access policy can't be empty")
+	}
+	if err := validateRole(role); err != nil {
+		return err
+	}
+	if expression != "" {
+		if _, err := ParsePolicy(expression); err != nil {
+			return err
+		}
+	}
+	return updateDirectoryAccess(ctx, key, nil, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		directory.SetPolicy(role, mspID, expression)
+		return nil
+	})
+}
+
+//ExpiringCooperatorGrants List the time-bounded cooperator grants of the directories managed by the caller which
+//expire within the given number of seconds.
+func (s *SmartContract) ExpiringCooperatorGrants(ctx contractapi.TransactionContextInterface, withinSeconds int64) ([]*ExpiringGrant, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := listManagedDirectories(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]*ExpiringGrant, 0)
+	for _, item := range items {
+		for _, cooperator := range item.Directory.Cooperators {
+			term, ok := item.Directory.CooperatorTerms[cooperator]
+			if !ok || term.DueDate == 0 {
+				continue
+			}
+			if term.DueDate <= timestamp.Seconds || term.DueDate > timestamp.Seconds+withinSeconds {
+				continue
+			}
+			grants = append(grants, &ExpiringGrant{
+				Key:           item.Key,
+				DirectoryName: item.Directory.Name,
+				Id:            cooperator,
+				Name:          item.Directory.IDNameMap[cooperator],
+				Role:          CooperatorRole,
+				DueDate:       term.DueDate,
+			})
+		}
+	}
+	return grants, nil
+}
+
+//CreateShareLink Create a link granting role on a directory and its children to whoever redeems it. The secret of the
+//link is read from the transient field "secret". The link expires at expiry unless it is zero, and can be redeemed
+//maxUses times unless it is zero.
+func (s *SmartContract) CreateShareLink(ctx contractapi.TransactionContextInterface, key string, role string, expiry int64, maxUses int64) (*ShareLink, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	if err = validateRole(role); err != nil {
+		return nil, err
+	}
+	if expiry < 0 || maxUses < 0 {
+		return nil, fmt.Errorf("expiry and maximum uses can't be negative")
+	}
+	if expiry > 0 && expiry <= timestamp.Seconds {
+		return nil, fmt.Errorf("expiry must be in the future")
+	}
+	secret, err := readShareLinkSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// Redemption re-checks the creator against the access lists, so rights from organization grants or policies, which
+	// depend on the certificate of the creator, can't be passed on.
+	ok, err := directory.PrincipalHasPrivilege(ctx, id, Cooperator, timestamp.Seconds)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("only cooperators listed directly or through a group can create share links")
+	}
+
+	link := &ShareLink{
+		Id:      SHA256(secret),
+		Key:     key,
+		Role:    role,
+		Creator: id,
+		Date:    timestamp.Seconds,
+		DueDate: expiry,
+		MaxUses: maxUses,
+	}
+	if _, err = getShareLink(ctx, link.Id); err == nil {
+		return nil, fmt.Errorf("share link already exists")
+	}
+	if err = putShareLink(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+//RedeemShareLink Gain the role of a share link. The secret is read from the transient field "secret", which keeps it
+//out of the ledger. The role is only granted on directories the creator of the link is still a cooperator of.
+func (s *SmartContract) RedeemShareLink(ctx contractapi.TransactionContextInterface) (*Directory, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := readShareLinkSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := getShareLink(ctx, SHA256(secret))
+	if err != nil {
+		return nil, err
+	}
+	if err = link.CheckRedeemable(timestamp.Seconds); err != nil {
+		return nil, err
+	}
+	directory, err := getDirectory(ctx, link.Key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.PrincipalHasPrivilege(ctx, link.Creator, Cooperator, timestamp.Seconds)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("creator of share link is no longer a cooperator")
+	}
+
+	grant := func(directory *Directory, ids []string, names []string, timestamp int64) error {
+		limit, err := directory.GrantDueDate(ctx, link.Creator, timestamp)
+		if err != nil {
+			return err
+		}
+		if link.Role == CooperatorRole {
+			return directory.AddCooperatorsUntil(ids, names, limit)
+		}
+		date, err := directory.SubscriptionDueDate(timestamp, 0, 0)
+		if err != nil {
+			return err
+		}
+		if limit > 0 && date > limit {
+			date = limit
+		}
+		directory.AddSubscribers(ids, names, date)
+		return nil
+	}
+	if err = grantDirectoryAccess(ctx, link.Key, []string{id}, true, link.Creator, grant); err != nil {
+		return nil, err
+	}
+
+	link.Uses++
+	if err = putShareLink(ctx, link); err != nil {
+		return nil, err
 	}
 
-	return updateIteration(ctx, key, ids, names, timestamp.Seconds, recursive, action)
+	names, err := getNameByID(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	if err = grant(directory, []string{id}, names, timestamp.Seconds); err != nil {
+		return nil, err
+	}
+	return directory, nil
 }
 
-func updateIteration(ctx contractapi.TransactionContextInterface, dirKey string, ids, names []string, timestamp int64, recursive bool, action Action) error {
-	dir, err := getDirectory(ctx, dirKey)
+//RevokeShareLink Delete a share link of a directory.
+func (s *SmartContract) RevokeShareLink(ctx contractapi.TransactionContextInterface, key string, linkID string) error {
+	directory, err := getDirectory(ctx, key)
 	if err != nil {
 		return err
 	}
-	ok, err := dir.CheckPrivilege(ctx, Cooperator)
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
 	if err != nil {
 		return err
 	}
@@ -454,75 +2188,413 @@ func updateIteration(ctx contractapi.TransactionContextInterface, dirKey string,
 		return privilegeError
 	}
 
-	action(dir, ids, names, timestamp)
-	if err = dir.Save(ctx, dirKey); err != nil {
+	link, err := getShareLink(ctx, linkID)
+	if err != nil {
 		return err
 	}
-	if recursive {
-		for _, dirKey := range dir.Directories {
-			err = updateIteration(ctx, dirKey, ids, names, timestamp, recursive, action)
-			if err != nil {
-				return err
-			}
-		}
+	if link.Key != key {
+		return fmt.Errorf("share link doesn't belong to directory")
 	}
-	return nil
+	return deleteShareLink(ctx, link)
 }
 
-func (s *SmartContract) AddSubscribers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
-	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) {
-		directory.AddSubscribers(ids, names, timestamp+validity)
-	})
+//ListShareLinks List the share links of a directory.
+func (s *SmartContract) ListShareLinks(ctx contractapi.TransactionContextInterface, key string) ([]*ShareLink, error) {
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(directoryShareLinkIndex, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	links := make([]*ShareLink, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var linkID string
+		if err = json.Unmarshal(kv.GetValue(), &linkID); err != nil {
+			return nil, err
+		}
+		link, err := getShareLink(ctx, linkID)
+		if err != nil {
+			continue
+		}
+		links = append(links, link)
+	}
+	return links, nil
 }
 
-func (s *SmartContract) AddCooperators(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
-	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) {
-		directory.AddCooperators(ids, names)
+//ShareFile Give users or groups read access to a single file of a directory, without exposing the other files.
+func (s *SmartContract) ShareFile(ctx contractapi.TransactionContextInterface, key string, fileName string, ids []string) (*Directory, error) {
+	return updateFileShares(ctx, key, fileName, ids, func(directory *Directory) error {
+		if _, err := getNameByID(ctx, ids); err != nil {
+			return err
+		}
+		return directory.ShareFile(fileName, ids)
 	})
 }
 
-func (s *SmartContract) RemoveSubscribers(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
-	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) {
-		directory.RemoveSubscribers(ids)
+//UnshareFile Revoke the access to a single file.
+func (s *SmartContract) UnshareFile(ctx contractapi.TransactionContextInterface, key string, fileName string, ids []string) (*Directory, error) {
+	return updateFileShares(ctx, key, fileName, ids, func(directory *Directory) error {
+		directory.UnshareFile(fileName, ids)
+		return nil
 	})
 }
 
-func (s *SmartContract) RemoveCooperators(ctx contractapi.TransactionContextInterface, key string, ids []string, recursive bool) error {
-	return updateDirectoryAccess(ctx, key, ids, recursive, func(directory *Directory, ids []string, names []string, timestamp int64) {
-		directory.RemoveCooperators(ids)
-	})
+func updateFileShares(ctx contractapi.TransactionContextInterface, key string, fileName string, ids []string, action func(directory *Directory) error) (*Directory, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if err = callerAuthorizer(ctx, Cooperator)(directory, timestamp.Seconds); err != nil {
+		return nil, err
+	}
+
+	if err = prepareWrite(ctx, key, directory); err != nil {
+		return nil, err
+	}
+	if err = action(directory); err != nil {
+		return nil, err
+	}
+	if err = directory.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	if err = updateFileShareIndex(ctx, key, directory, fileName, ids); err != nil {
+		return nil, err
+	}
+	return directory, nil
 }
 
-func (s *SmartContract) Subscribe(ctx contractapi.TransactionContextInterface, key string) (*Directory, error) {
+//ReadSharedFile Read a single file of a directory. It succeeds if the file is shared with the caller or a group of the
+//caller, or if the caller can read the whole directory.
+func (s *SmartContract) ReadSharedFile(ctx contractapi.TransactionContextInterface, key string, fileName string) (*FileMeta, error) {
 	id, err := getUserID(ctx)
 	if err != nil {
 		return nil, err
 	}
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	file := directory.GetFile(fileName)
+	if file == nil {
+		return nil, fmt.Errorf("file doesn't exist")
+	}
 
-	ids := []string{id}
+	ok, err := directory.CheckPrivilege(ctx, Subscriber)
+	if err != nil {
+		return nil, err
+	}
+	if ok || directory.IsReadableByKey() {
+		return file, nil
+	}
 
-	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	principals, err := getPrincipals(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, principal := range principals {
+		if directory.IsFileSharedWith(fileName, principal) {
+			return file, nil
+		}
+	}
+	return nil, privilegeError
+}
+
+//SetUserQuota Limit the storage of a user in bytes. Zero removes the limit. Only admin identities can set user quotas.
+func (s *SmartContract) SetUserQuota(ctx contractapi.TransactionContextInterface, userID string, quota int64) (*Usage, error) {
+	if err := validateQuota(quota); err != nil {
+		return nil, err
+	}
+	ok, err := isAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
+	}
+
+	userProfile, err := getUserProfile(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	userProfile.Quota = quota
+	if err = PutJsonState(ctx, userID, userProfile); err != nil {
+		return nil, err
+	}
+	return &Usage{Target: userID, Usage: userProfile.Usage, Quota: userProfile.Quota}, nil
+}
 
+//SetDirectoryQuota Limit the size of the files of a directory in bytes. Zero removes the limit. The quota only covers
+//the files of the directory itself, not those of its subdirectories, and copies of the directory keep it. The storage
+//of a whole tree is limited by the quota of its owner. Only the creator of the directory and admin identities can set
+//directory quotas.
+func (s *SmartContract) SetDirectoryQuota(ctx contractapi.TransactionContextInterface, key string, quota int64) (*Usage, error) {
+	if err := validateQuota(quota); err != nil {
+		return nil, err
+	}
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	directory, err := getDirectory(ctx, key)
 	if err != nil {
 		return nil, err
 	}
+	ok, err := isAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok && !directory.IsCreator(id) {
+		return nil, privilegeError
+	}
 
-	if directory.IsSubscribers(id, timestamp.Seconds) {
-		return directory, nil
+	directory.Quota = quota
+	if err = directory.Save(ctx, key); err != nil {
+		return nil, err
 	}
+	return &Usage{Target: key, Usage: directory.Usage, Quota: directory.Quota}, nil
+}
 
-	if directory.IsCreator(id) || directory.IsCooperator(id) || directory.Visibility == Public {
-		err = s.AddSubscribers(ctx, key, ids, true)
+//GetUsage Read the storage usage of a user or a directory. Users can only read their own usage unless the caller is
+//an admin. Directories require read access.
+func (s *SmartContract) GetUsage(ctx contractapi.TransactionContextInterface, target string) (*Usage, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if profile, err := getUserProfile(ctx, target); err == nil {
+		ok, err := isAdmin(ctx)
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		return nil, fmt.Errorf("can't access private directory")
+		if !ok && id != target {
+			return nil, privilegeError
+		}
+		return &Usage{Target: target, Usage: profile.Usage, Quota: profile.Quota}, nil
+	}
+
+	directory, err := getDirectory(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Subscriber)
+	if err != nil {
+		return nil, err
+	}
+	if !ok && !directory.IsReadableByKey() {
+		return nil, privilegeError
+	}
+	return &Usage{Target: target, Usage: directory.Usage, Quota: directory.Quota}, nil
+}
+
+//AddTags Tag a file of a directory, or the directory itself if fileName is empty.
+func (s *SmartContract) AddTags(ctx contractapi.TransactionContextInterface, key string, fileName string, tags []string) (*Directory, error) {
+	if err := validateTags(tags); err != nil {
+		return nil, err
+	}
+	return updateTags(ctx, key, fileName, tags, func(directory *Directory) error {
+		return directory.AddTags(fileName, tags)
+	})
+}
+
+//RemoveTags Remove tags from a file of a directory, or from the directory itself if fileName is empty.
+func (s *SmartContract) RemoveTags(ctx contractapi.TransactionContextInterface, key string, fileName string, tags []string) (*Directory, error) {
+	return updateTags(ctx, key, fileName, tags, func(directory *Directory) error {
+		return directory.RemoveTags(fileName, tags)
+	})
+}
+
+func updateTags(ctx contractapi.TransactionContextInterface, key string, fileName string, tags []string, action func(directory *Directory) error) (*Directory, error) {
+	directory, err := getDirectory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := directory.CheckPrivilege(ctx, Cooperator)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, privilegeError
 	}
 
+	if fileName != "" {
+		if err = prepareWrite(ctx, key, directory); err != nil {
+			return nil, err
+		}
+	}
+	if err = action(directory); err != nil {
+		return nil, err
+	}
+	if err = directory.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	if err = updateTagIndex(ctx, key, directory, fileName, tags); err != nil {
+		return nil, err
+	}
 	return directory, nil
 }
+
+//FindByTag Find the directories and files carrying a tag. Directories and their files are found if the caller can
+//read the directory or the directory is public. Files shared with the caller are found as well.
+func (s *SmartContract) FindByTag(ctx contractapi.TransactionContextInterface, tag string) ([]*TaggedItem, error) {
+	id, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	principals, err := getPrincipals(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return listTaggedItems(ctx, tag, func(directory *Directory, item *TaggedItem) (bool, error) {
+		ok, err := directory.CheckPrivilege(ctx, Subscriber)
+		if err != nil {
+			return false, err
+		}
+		if ok || directory.IsListed() {
+			return true, nil
+		}
+		if item.FileName == "" {
+			return false, nil
+		}
+		for _, principal := range principals {
+			if directory.IsFileSharedWith(item.FileName, principal) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+//SearchByName Find directories and files below rootKey whose name contains query, or starts with it if prefixOnly is
+//set, ignoring case. An empty rootKey searches below the private directory of the caller. Only directories the caller
+//can read, or public ones, are returned. Matches are looked up in the name index, which holds the content of a mount
+//under its target. At most limit results are returned, and the results are marked as truncated if there are more.
+func (s *SmartContract) SearchByName(ctx contractapi.TransactionContextInterface, query string, rootKey string, limit int, prefixOnly bool) (*SearchResults, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query can't be empty")
+	}
+	query = strings.ToLower(query)
+	limit = searchLimit(limit)
+	if rootKey == "" {
+		id, err := getUserID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		profile, err := getUserProfile(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		rootKey = profile.Private
+	}
+
+	results := &SearchResults{Results: make([]*SearchResult, 0)}
+	directories := make(map[string]*Directory)
+	found := make(map[string]bool)
+	err := listNamedItems(ctx, query, prefixOnly, func(item *NamedItem) (bool, error) {
+		if item.Key == rootKey && item.FileName == "" || found[item.Key+"/"+item.FileName] {
+			return true, nil
+		}
+		directory, ok := directories[item.Key]
+		if !ok {
+			candidate, err := getDirectory(ctx, item.Key)
+			if err == nil {
+				readable, err := candidate.CheckPrivilege(ctx, Subscriber)
+				if err != nil {
+					return false, err
+				}
+				below, err := isBelow(ctx, item.Key, rootKey)
+				if err != nil {
+					return false, err
+				}
+				if (readable || candidate.IsListed()) && below {
+					directory = candidate
+				}
+			}
+			directories[item.Key] = directory
+		}
+		if directory == nil {
+			return true, nil
+		}
+
+		result := &SearchResult{Key: item.Key, FileName: item.FileName}
+		name := directory.Name
+		if item.FileName == "" {
+			result.Directory = directory
+		} else if result.File = directory.GetFile(item.FileName); result.File != nil {
+			name = result.File.Name
+		} else {
+			return true, nil
+		}
+		if !matchName(name, query, prefixOnly) {
+			return true, nil
+		}
+		if len(results.Results) >= limit {
+			results.Truncated = true
+			return false, nil
+		}
+		found[item.Key+"/"+item.FileName] = true
+		results.Results = append(results.Results, result)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+//ReindexNames Index the names of the directories and their files, for directories saved before the name index
+//existed.
+func (s *SmartContract) ReindexNames(ctx contractapi.TransactionContextInterface, keys []string) error {
+	for _, key := range keys {
+		directory, err := getDirectory(ctx, key)
+		if err != nil {
+			return err
+		}
+		if err = updateNameIndex(ctx, key, &Directory{}, directory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//ExecuteBatch Run operations in order within one transaction. Later operations see the writes of earlier ones. If an
+//operation fails the whole transaction fails, so either every operation takes effect or none does. Only the event of
+//the last operation setting one is emitted.
+func (s *SmartContract) ExecuteBatch(ctx contractapi.TransactionContextInterface, ops []*BatchOperation) ([]*BatchResult, error) {
+	if len(ops) > maxBatchSize {
+		return nil, fmt.Errorf("batch exceeds the maximum of %d operations", maxBatchSize)
+	}
+	batchCtx := newBatchContext(ctx)
+	results := make([]*BatchResult, 0)
+	for index, op := range ops {
+		args, err := resolveBatchArgs(op.Args, results)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %v", index, err)
+		}
+		result, err := executeBatchOperation(batchCtx, s, op.Method, args)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %v", index, err)
+		}
+		results = append(results, &BatchResult{Method: op.Method, Result: result})
+	}
+	return results, nil
+}
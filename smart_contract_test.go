@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"testing"
 )
 
@@ -14,3 +15,92 @@ func TestSmartContract_ReadUserProfile(t *testing.T) {
 	a := make(map[string]bool)
 	fmt.Println(a["a"])
 }
+
+func TestNewChaincode(t *testing.T) {
+	if _, err := contractapi.NewChaincode(&SmartContract{}); err != nil {
+		t.Errorf("fail to create chaincode: %v", err)
+	}
+}
+
+func TestCreateDirectory_AttachesToParent(t *testing.T) {
+	l := newTestLedger(t)
+	alice, carol := l.user("alice"), l.user("carol")
+	parent := l.directory(alice, "parent", Private)
+
+	key, err := l.contract.CreateDirectory(l.as(alice), "child", Private, parent, false)
+	l.must(err)
+	if children := l.read(parent).Directories; len(children) != 1 || children[0] != key {
+		t.Errorf("child should be attached to the parent, got %v", children)
+	}
+	parents, err := listParents(l.as(alice), key)
+	l.must(err)
+	if len(parents) != 1 || parents[0] != parent {
+		t.Errorf("parent should be indexed, got %v", parents)
+	}
+	if !l.read(key).IsCreator(alice.ID()) {
+		t.Errorf("caller should own the child")
+	}
+
+	if _, err = l.contract.CreateDirectory(l.as(carol), "intruder", Private, parent, false); err != privilegeError {
+		t.Errorf("a stranger should not create a directory in the parent, got %v", err)
+	}
+	if children := l.read(parent).Directories; len(children) != 1 {
+		t.Errorf("a rejected directory should not be attached, got %v", children)
+	}
+}
+
+func TestCreateDirectory_InheritAccess(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol := l.user("alice"), l.user("bob"), l.user("carol")
+	parent := l.directory(alice, "parent", Private)
+	l.must(l.contract.AddCooperators(l.as(alice), parent, []string{bob.ID()}, false))
+	l.must(l.contract.AddSubscribers(l.as(alice), parent, []string{carol.ID()}, false))
+
+	inherited, err := l.contract.CreateDirectory(l.as(bob), "inherited", Private, parent, true)
+	l.must(err)
+	directory := l.read(inherited)
+	if !directory.IsCreator(bob.ID()) {
+		t.Errorf("caller should own the child")
+	}
+	for _, id := range []string{alice.ID(), bob.ID()} {
+		if !directory.IsCooperator(id) {
+			t.Errorf("cooperator %s of the parent should be inherited", id)
+		}
+	}
+	if !directory.IsSubscribers(carol.ID(), l.now) {
+		t.Errorf("subscriber of the parent should be inherited")
+	}
+
+	plain, err := l.contract.CreateDirectory(l.as(bob), "plain", Private, parent, false)
+	l.must(err)
+	directory = l.read(plain)
+	if directory.IsCooperator(alice.ID()) || directory.IsSubscribers(carol.ID(), l.now) {
+		t.Errorf("access should only be inherited on request")
+	}
+}
+
+func TestCreateDirectory_NameConflict(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	parent := l.directory(alice, "parent", Private)
+
+	_, err := l.contract.CreateDirectory(l.as(alice), "reports", Private, parent, false)
+	l.must(err)
+	if _, err = l.contract.CreateDirectory(l.as(alice), "reports", Private, parent, false); err == nil || err.Error() != "directory name conflict" {
+		t.Errorf("a second child of the same name should be rejected, got %v", err)
+	}
+	_, err = l.contract.CreateDirectory(l.as(alice), "archive", Private, parent, false)
+	l.must(err)
+	if children := l.read(parent).Directories; len(children) != 2 {
+		t.Errorf("parent should have two children, got %v", children)
+	}
+}
+
+func TestCreateDirectory_InheritAccessRequiresParent(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+
+	if _, err := l.contract.CreateDirectory(l.as(alice), "orphan", Private, "", true); err == nil {
+		t.Errorf("access should not be inherited without a parent")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const subscriptionRequestIndex = "subscriptionRequest"
+
+type SubscriptionRequest struct {
+	Key           string `json:"key"`
+	Requester     string `json:"requester"`
+	RequesterName string `json:"requesterName"`
+	Message       string `json:"message"`
+	Date          int64  `json:"date"`
+}
+
+// SubscriptionEvent is the payload of the events emitted when a subscription request is settled or a subscription is
+// purchased.
+type SubscriptionEvent struct {
+	Key       string `json:"key"`
+	Requester string `json:"requester"`
+	Price     int64  `json:"price,omitempty"`
+	DueDate   int64  `json:"dueDate,omitempty"`
+}
+
+func subscriptionRequestKey(ctx contractapi.TransactionContextInterface, key, requester string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(subscriptionRequestIndex, []string{key, requester})
+}
+
+func getSubscriptionRequest(ctx contractapi.TransactionContextInterface, key, requester string) (string, *SubscriptionRequest, error) {
+	requestKey, err := subscriptionRequestKey(ctx, key, requester)
+	if err != nil {
+		return "", nil, err
+	}
+	request := new(SubscriptionRequest)
+	if err = GetJsonState(ctx, requestKey, request); err != nil {
+		return "", nil, fmt.Errorf("subscription request doesn't exist")
+	}
+	return requestKey, request, nil
+}
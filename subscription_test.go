@@ -0,0 +1,110 @@
+package main
+
+import "testing"
+
+func TestPurchaseSubscription_SkipsForeignChildren(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol := l.user("alice"), l.user("bob"), l.user("carol")
+	admin := newTestAdmin("admin")
+	shop := l.directory(alice, "shop", Private)
+	victim := l.directory(carol, "victim", Private)
+
+	_, err := l.contract.AddDirectories(l.as(alice), shop, []string{victim})
+	l.must(err)
+	_, err = l.contract.SetSubscriptionPrice(l.as(alice), shop, 10, 100)
+	l.must(err)
+	_, err = l.contract.Mint(l.as(admin), bob.ID(), 10)
+	l.must(err)
+	_, err = l.contract.PurchaseSubscription(l.as(bob), shop)
+	l.must(err)
+
+	if l.read(shop).GetSubscriber(bob.ID()) == nil {
+		t.Errorf("buyer should subscribe to the directory")
+	}
+	if l.read(victim).GetSubscriber(bob.ID()) != nil {
+		t.Errorf("buyer should not subscribe to a child the seller has no rights on")
+	}
+}
+
+func TestRequestSubscription_Approve(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob, carol := l.user("alice"), l.user("bob"), l.user("carol")
+	key := l.directory(alice, "reports", Private)
+
+	l.must(l.contract.RequestSubscription(l.as(bob), key, "please"))
+	requests, err := l.contract.ListPendingRequests(l.as(alice), key)
+	l.must(err)
+	if len(requests) != 1 || requests[0].Requester != bob.ID() || requests[0].Message != "please" {
+		t.Fatalf("request should be pending, got %v", requests)
+	}
+	if _, err = l.contract.ListPendingRequests(l.as(carol), key); err != privilegeError {
+		t.Errorf("only cooperators should list requests, got %v", err)
+	}
+	if err = l.contract.ApproveSubscription(l.as(carol), key, bob.ID()); err == nil {
+		t.Errorf("only cooperators should approve requests")
+	}
+
+	l.must(l.contract.ApproveSubscription(l.as(alice), key, bob.ID()))
+	if !l.read(key).IsSubscribers(bob.ID(), l.now) {
+		t.Errorf("approved requester should subscribe to the directory")
+	}
+	if requests, _ = l.contract.ListPendingRequests(l.as(alice), key); len(requests) != 0 {
+		t.Errorf("approved request should be dropped")
+	}
+	if err = l.contract.RequestSubscription(l.as(bob), key, ""); err == nil {
+		t.Errorf("subscriber should not request a subscription again")
+	}
+}
+
+func TestRequestSubscription_Reject(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	key := l.directory(alice, "reports", Private)
+
+	l.must(l.contract.RequestSubscription(l.as(bob), key, ""))
+	if err := l.contract.RejectSubscription(l.as(bob), key, bob.ID()); err != privilegeError {
+		t.Errorf("requester should not reject its own request, got %v", err)
+	}
+	l.must(l.contract.RejectSubscription(l.as(alice), key, bob.ID()))
+	if err := l.contract.ApproveSubscription(l.as(alice), key, bob.ID()); err == nil {
+		t.Errorf("rejected request should not be approved")
+	}
+	if l.read(key).IsSubscribers(bob.ID(), l.now) {
+		t.Errorf("rejected requester should not subscribe")
+	}
+}
+
+func TestRequestSubscription_PublicDirectory(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	for _, visibility := range []string{Public, Unlisted} {
+		key := l.directory(alice, "reports", visibility)
+		if err := l.contract.RequestSubscription(l.as(bob), key, ""); err == nil {
+			t.Errorf("requests for %s directories should be rejected", visibility)
+		}
+		_, err := l.contract.Subscribe(l.as(bob), key)
+		l.must(err)
+		if !l.read(key).IsSubscribers(bob.ID(), l.now) {
+			t.Errorf("anyone should subscribe to %s directories", visibility)
+		}
+	}
+}
+
+func TestSubscribe_SkipsPrivateChildren(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	key := l.directory(alice, "reports", Public)
+	open := l.directory(alice, "open", Unlisted)
+	private := l.directory(alice, "private", Private)
+	_, err := l.contract.AddDirectories(l.as(alice), key, []string{open, private})
+	l.must(err)
+
+	_, err = l.contract.Subscribe(l.as(bob), key)
+	l.must(err)
+	if !l.read(open).IsSubscribers(bob.ID(), l.now) {
+		t.Errorf("subscription should cover readable children")
+	}
+	if l.read(private).IsSubscribers(bob.ID(), l.now) {
+		t.Errorf("subscription should not cover private children")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"strings"
+)
+
+const tagIndex = "tag"
+
+const (
+	maxTags      = 32
+	maxTagLength = 64
+)
+
+// TaggedItem is an entry of the tag index. It refers to a directory, or to a file of it if FileName is set. Directory
+// and File are only filled in when the entry is found.
+type TaggedItem struct {
+	Key       string     `json:"key"`
+	FileName  string     `json:"fileName,omitempty"`
+	Directory *Directory `json:"directory,omitempty"`
+	File      *FileMeta  `json:"file,omitempty"`
+}
+
+func validateTags(tags []string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("more than %d tags", maxTags)
+	}
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("tag can't be empty")
+		}
+		if len(tag) > maxTagLength {
+			return fmt.Errorf("tag %s exceeds %d bytes", tag, maxTagLength)
+		}
+	}
+	return nil
+}
+
+func addTags(tags []string, added []string) []string {
+	for _, tag := range added {
+		if !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func removeTags(tags []string, removed []string) []string {
+	remains := make([]string, 0)
+	for _, tag := range tags {
+		if !hasTag(removed, tag) {
+			remains = append(remains, tag)
+		}
+	}
+	return remains
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, i := range tags {
+		if i == tag {
+			return true
+		}
+	}
+	return false
+}
+
+//TagsOf Return the tags of a file of the directory, or of the directory itself if name is empty.
+func (d *Directory) TagsOf(name string) ([]string, error) {
+	if name == "" {
+		return d.Tags, nil
+	}
+	file := d.GetFile(name)
+	if file == nil {
+		return nil, fmt.Errorf("file doesn't exist")
+	}
+	return file.Tags, nil
+}
+
+//AddTags Tag a file of the directory, or the directory itself if name is empty.
+func (d *Directory) AddTags(name string, tags []string) error {
+	if name == "" {
+		if err := validateTags(addTags(append([]string{}, d.Tags...), tags)); err != nil {
+			return err
+		}
+		d.Tags = addTags(d.Tags, tags)
+		return nil
+	}
+	file := d.GetFile(name)
+	if file == nil {
+		return fmt.Errorf("file doesn't exist")
+	}
+	if err := validateTags(addTags(append([]string{}, file.Tags...), tags)); err != nil {
+		return err
+	}
+	file.Tags = addTags(file.Tags, tags)
+	return nil
+}
+
+//RemoveTags Remove tags from a file of the directory, or from the directory itself if name is empty.
+func (d *Directory) RemoveTags(name string, tags []string) error {
+	if name == "" {
+		d.Tags = removeTags(d.Tags, tags)
+		return nil
+	}
+	file := d.GetFile(name)
+	if file == nil {
+		return fmt.Errorf("file doesn't exist")
+	}
+	file.Tags = removeTags(file.Tags, tags)
+	return nil
+}
+
+// updateTagIndex synchronizes the tag index of the given tags with a file of directory, or with directory itself if
+// name is empty.
+func updateTagIndex(ctx contractapi.TransactionContextInterface, key string, directory *Directory, name string, tags []string) error {
+	current, err := directory.TagsOf(name)
+	if err != nil {
+		current = nil
+	}
+	for _, tag := range tags {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(tagIndex, []string{tag, key, name})
+		if err != nil {
+			return err
+		}
+		if !hasTag(current, tag) {
+			if err = putIndexEntry(ctx, indexKey, nil, false); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = PutJsonState(ctx, indexKey, &TaggedItem{Key: key, FileName: name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexAllTags adds the tags of directory and all of its files to the tag index.
+func indexAllTags(ctx contractapi.TransactionContextInterface, key string, directory *Directory) error {
+	if err := updateTagIndex(ctx, key, directory, "", directory.Tags); err != nil {
+		return err
+	}
+	for _, file := range directory.Files {
+		if err := updateTagIndex(ctx, key, directory, file.Name, file.Tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listTaggedItems returns the entries of the tag index for a tag. Entries whose directory or file is gone or no longer
+// carries the tag, and entries failing check, are skipped.
+func listTaggedItems(ctx contractapi.TransactionContextInterface, tag string, check func(directory *Directory, item *TaggedItem) (bool, error)) ([]*TaggedItem, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(tagIndex, []string{tag})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	items := make([]*TaggedItem, 0)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		item := new(TaggedItem)
+		if err = json.Unmarshal(kv.GetValue(), item); err != nil {
+			return nil, err
+		}
+		directory, err := getDirectory(ctx, item.Key)
+		if err != nil {
+			continue
+		}
+		tags, err := directory.TagsOf(item.FileName)
+		if err != nil || !hasTag(tags, tag) {
+			continue
+		}
+		ok, err := check(directory, item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if item.FileName == "" {
+			item.Directory = directory
+		} else {
+			item.File = directory.GetFile(item.FileName)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
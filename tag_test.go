@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDirectory_AddTags(t *testing.T) {
+	d := NewDirectory("test", "123", "nmsl", Public, 1231231)
+	d.AddFiles([]*FileMeta{{Name: "a"}})
+
+	if err := d.AddTags("", []string{"project", "draft", "project"}); err != nil || len(d.Tags) != 2 {
+		t.Errorf("fail to tag directory: %v %v", d.Tags, err)
+	}
+	if err := d.AddTags("a", []string{"draft"}); err != nil {
+		t.Errorf("fail to tag file: %v", err)
+	}
+	if tags, _ := d.TagsOf("a"); !hasTag(tags, "draft") {
+		t.Errorf("file should carry the tag")
+	}
+	if d.AddTags("b", []string{"draft"}) == nil {
+		t.Errorf("tagging a missing file should fail")
+	}
+	if d.AddTags("", []string{" "}) == nil || d.AddTags("", []string{strings.Repeat("a", maxTagLength+1)}) == nil {
+		t.Errorf("invalid tags should be rejected")
+	}
+
+	if err := d.RemoveTags("", []string{"draft"}); err != nil || hasTag(d.Tags, "draft") || !hasTag(d.Tags, "project") {
+		t.Errorf("fail to remove tag: %v", d.Tags)
+	}
+}
+
+func TestCopyOnWriteMount_IndexesMaterializedTags(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	target := l.directory(alice, "target", Private)
+	parent := l.directory(alice, "parent", Private)
+	_, err := l.contract.AddFile(l.as(alice), target, []*FileMeta{{Name: "q1.pdf", Tags: []string{"report"}}})
+	l.must(err)
+	mount, err := l.contract.CreateMount(l.as(alice), parent, target, "mount", CopyOnWriteMount)
+	l.must(err)
+
+	_, err = l.contract.AddFile(l.as(alice), mount, []*FileMeta{{Name: "q2.pdf"}})
+	l.must(err)
+	items, err := l.contract.FindByTag(l.as(alice), "report")
+	l.must(err)
+	found := false
+	for _, item := range items {
+		found = found || item.Key == mount && item.FileName == "q1.pdf"
+	}
+	if !found {
+		t.Errorf("files taken over by a copy-on-write mount should be indexed by their tags")
+	}
+}
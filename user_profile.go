@@ -1,9 +1,41 @@
 package main
 
+import (
+	"fmt"
+	"math"
+)
+
 type UserProfile struct {
-	Id      string `json:"id"`
-	Name    string `json:"name"`
-	Private string `json:"private"`
-	//Share         string `json:"share"`
-	//Subscriptions string `json:"subscriptions"`
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	Private       string `json:"private"`
+	Share         string `json:"share"`
+	Subscriptions string `json:"subscriptions"`
+	Balance       int64  `json:"balance"`
+	Usage         int64  `json:"usage"`
+	Quota         int64  `json:"quota"`
+}
+
+var balanceError = fmt.Errorf("insufficient balance")
+
+func (u *UserProfile) Credit(amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if u.Balance > math.MaxInt64-amount {
+		return fmt.Errorf("balance overflow")
+	}
+	u.Balance += amount
+	return nil
+}
+
+func (u *UserProfile) Debit(amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if u.Balance < amount {
+		return balanceError
+	}
+	u.Balance -= amount
+	return nil
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUserProfile_Credit(t *testing.T) {
+	profile := &UserProfile{Id: "1", Balance: 10}
+	if err := profile.Credit(5); err != nil || profile.Balance != 15 {
+		t.Errorf("fail to credit")
+	}
+	if err := profile.Credit(-1); err == nil {
+		t.Errorf("should reject negative amount")
+	}
+	profile.Balance = math.MaxInt64
+	if err := profile.Credit(1); err == nil {
+		t.Errorf("should reject overflow")
+	}
+}
+
+func TestUserProfile_Debit(t *testing.T) {
+	profile := &UserProfile{Id: "1", Balance: 10}
+	if err := profile.Debit(4); err != nil || profile.Balance != 6 {
+		t.Errorf("fail to debit")
+	}
+	if err := profile.Debit(7); err != balanceError || profile.Balance != 6 {
+		t.Errorf("should reject debit exceeding balance")
+	}
+}
+
+func TestUserProfile_ChargeUsage(t *testing.T) {
+	profile := &UserProfile{Id: "1", Usage: 10, Quota: 20}
+	if err := profile.ChargeUsage(10); err != nil || profile.Usage != 20 {
+		t.Errorf("fail to charge usage within quota")
+	}
+	if err := profile.ChargeUsage(1); err != quotaError {
+		t.Errorf("should reject usage exceeding quota")
+	}
+	profile.Quota = 5
+	if err := profile.ChargeUsage(-30); err != nil || profile.Usage != 0 {
+		t.Errorf("releasing usage should always succeed")
+	}
+}
+
+func TestMint_RequiresAdminAttribute(t *testing.T) {
+	l := newTestLedger(t)
+	alice := l.user("alice")
+	member := newTestIdentity("member")
+	member.mspID = testAdminMSP
+	if _, err := l.contract.Mint(l.as(member), alice.ID(), 10); err != privilegeError {
+		t.Errorf("identity without the admin attribute should not mint")
+	}
+	foreign := newTestAdmin("foreign")
+	foreign.mspID = "AnyOtherMSP"
+	if _, err := l.contract.Mint(l.as(foreign), alice.ID(), 10); err != privilegeError {
+		t.Errorf("admin attribute issued by another organization should not mint")
+	}
+	admin := newTestAdmin("admin")
+	if profile, err := l.contract.Mint(l.as(admin), alice.ID(), 10); err != nil || profile.Balance != 10 {
+		t.Errorf("admin should mint: %v", err)
+	}
+}
+
+func TestTransfer_RejectsDirectoryKey(t *testing.T) {
+	l := newTestLedger(t)
+	alice, bob := l.user("alice"), l.user("bob")
+	admin := newTestAdmin("admin")
+	key := l.directory(alice, "precious", Private)
+	_, err := l.contract.Mint(l.as(admin), bob.ID(), 1)
+	l.must(err)
+
+	if _, err = l.contract.Transfer(l.as(bob), key, 1); err == nil {
+		t.Errorf("transfer to a directory key should fail")
+	}
+	if _, err = l.contract.Mint(l.as(admin), key, 1); err == nil {
+		t.Errorf("minting to a directory key should fail")
+	}
+	if err = l.contract.TransferOwnership(l.as(alice), l.directory(alice, "other", Private), key, false, false); err == nil {
+		t.Errorf("transferring ownership to a directory key should fail")
+	}
+	if l.read(key).Name != "precious" {
+		t.Errorf("directory should be left untouched")
+	}
+}
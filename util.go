@@ -4,7 +4,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"os"
 )
 
 func SHA256(message string) string {
@@ -45,11 +47,13 @@ func getUserID(ctx contractapi.TransactionContextInterface) (string, error) {
 	return SHA256Bytes(cert.Raw)[0:8], nil
 }
 
+// getUserProfile reads the profile of a user. Profiles share the key space with directories and indexes, so a
+// document which isn't the profile of id is rejected rather than read, and later overwritten, as one.
 func getUserProfile(ctx contractapi.TransactionContextInterface, id string) (*UserProfile, error) {
 	userProfile := new(UserProfile)
 	err := GetJsonState(ctx, id, userProfile)
-	if err != nil {
-		return nil, err
+	if err != nil || userProfile.Id != id {
+		return nil, fmt.Errorf("user %s doesn't exist", id)
 	}
 	return userProfile, nil
 }
@@ -67,3 +71,39 @@ func getIntersection(strings1, strings2 []string) []string {
 	}
 	return intersection
 }
+
+func SetJsonEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(name, bytes)
+}
+
+// AdminAttribute is the certificate attribute which marks the identities allowed to mint tokens and set user quotas.
+// Issue it with value "true" and ecert enabled, e.g. fabric-ca-client register --id.attrs 'fabric-fs.admin=true:ecert'.
+const AdminAttribute = "fabric-fs.admin"
+
+// AdminMSPVariable names the environment variable of the chaincode holding the MSP ID of the organization whose
+// identities can be admins. Any organization's CA can issue AdminAttribute, so it only counts for identities of that
+// organization. Without the variable nobody is an admin.
+const AdminMSPVariable = "FABRIC_FS_ADMIN_MSP"
+
+func isAdmin(ctx contractapi.TransactionContextInterface) (bool, error) {
+	adminMSPID := os.Getenv(AdminMSPVariable)
+	if adminMSPID == "" {
+		return false, nil
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, err
+	}
+	if mspID != adminMSPID {
+		return false, nil
+	}
+	value, found, err := ctx.GetClientIdentity().GetAttributeValue(AdminAttribute)
+	if err != nil {
+		return false, err
+	}
+	return found && value == "true", nil
+}